@@ -0,0 +1,8 @@
+data "gitops_instance" "by_id" {
+  instance_id = "123"
+}
+
+data "gitops_instance" "by_name" {
+  instance_name = "terraform provisioned test1"
+  bits_account  = 12341
+}
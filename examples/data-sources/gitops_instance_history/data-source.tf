@@ -0,0 +1,15 @@
+data "gitops_instance_history" "example" {
+  instance_id = "123"
+}
+
+# Who changed the instance, what and in which commit
+output "instance_changes" {
+  value = [
+    for change in data.gitops_instance_history.example.changes : {
+      timestamp      = change.timestamp
+      actor          = change.actor
+      changed_fields = change.changed_fields
+      commit_sha     = change.commit_sha
+    }
+  ]
+}
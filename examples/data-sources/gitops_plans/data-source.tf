@@ -0,0 +1,8 @@
+data "gitops_plans" "account" {
+  bits_account = 12341
+  stage        = "deployed"
+}
+
+output "instance_ids" {
+  value = [for plan in data.gitops_plans.account.plans : plan.instance_id]
+}
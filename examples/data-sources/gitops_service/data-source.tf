@@ -0,0 +1,13 @@
+data "gitops_service" "redis" {
+  name = "redis"
+}
+
+resource "gitops_instance" "cache" {
+  instance_name = "terraform provisioned cache"
+  orderer_id    = "your.email@address"
+  bits_account  = 12341
+  service_id    = data.gitops_service.redis.id
+  replica_count = data.gitops_service.redis.min_replica_count
+  version       = provider::gitops::resolve_version("3.2.*", data.gitops_service.redis.versions)
+  some_value    = "cache"
+}
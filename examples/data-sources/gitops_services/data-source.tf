@@ -0,0 +1,5 @@
+data "gitops_services" "catalog" {}
+
+output "service_names" {
+  value = { for service in data.gitops_services.catalog.services : service.name => service.id }
+}
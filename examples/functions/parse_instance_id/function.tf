@@ -0,0 +1,4 @@
+import {
+  to = gitops_instance.test1
+  id = provider::gitops::parse_instance_id("https://gitops.example.com/instances/123")
+}
@@ -0,0 +1,3 @@
+output "latest_3_2" {
+  value = provider::gitops::resolve_version("3.2.*", ["3.1.9", "3.2.4", "3.2.10"])
+}
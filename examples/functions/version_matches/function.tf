@@ -0,0 +1,3 @@
+output "is_supported" {
+  value = provider::gitops::version_matches("3.2.*", "3.2.7")
+}
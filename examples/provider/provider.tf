@@ -13,4 +13,57 @@ provider "gitops" {
   authz_listener_socket = "localhost:12345"
   scopes                = "openid email"
   #debug = true
+
+  default_labels = {
+    team = "platform"
+  }
+}
+
+# Non-interactive authentication of the client itself, e.g. in CI pipelines
+variable "gitops_client_secret" {
+  type      = string
+  sensitive = true
+}
+
+provider "gitops" {
+  alias          = "ci"
+  gitops_api_uri = "http://localhost:8000"
+  cache_path     = "/tmp/.gitops-tf-provider-ci"
+  grant_type     = "client_credentials"
+  client_id      = "gitops-ci"
+  client_secret  = var.gitops_client_secret
+  token_uri      = "https://idp.example.com/protocol/openid-connect/token"
+}
+
+# One alias per Gitops API environment. Configuring the production API
+# fails unless it reports environment prod.
+provider "gitops" {
+  alias          = "prod"
+  gitops_api_uri = "https://gitops-prod.example.com"
+  environment    = "prod"
+  cache_path     = "/tmp/.gitops-tf-provider"
+  grant_type     = "client_credentials"
+  client_id      = "gitops-ci"
+  client_secret  = var.gitops_client_secret
+  token_uri      = "https://idp.example.com/protocol/openid-connect/token"
+}
+
+# Workload identity, e.g. the OIDC ID token of a GitLab CI job written
+# to a file, exchanged for an access token without any client secret.
+# The tokens of short lived CI jobs need not outlive the Terraform run.
+provider "gitops" {
+  alias          = "workload"
+  gitops_api_uri = "http://localhost:8000"
+  cache_mode     = "memory"
+  grant_type     = "token_exchange"
+  client_id      = "gitops-ci"
+  id_token_file  = "/run/secrets/gitops_id_token"
+  token_uri      = "https://idp.example.com/protocol/openid-connect/token"
+}
+
+# Pre-issued access token, e.g. set via the GITOPS_TOKEN environment
+# variable by a wrapper script, without any OAuth2 configuration
+provider "gitops" {
+  alias          = "token"
+  gitops_api_uri = "http://localhost:8000"
 }
@@ -6,4 +6,15 @@ resource "gitops_instance" "test1" {
   replica_count = 3
   version       = "3.2.*"
   some_value    = "test instance 1"
-}
\ No newline at end of file
+
+  labels = {
+    app = "test1"
+  }
+
+  target_stages  = ["deployed"]
+  failure_stages = ["failed"]
+
+  timeouts = {
+    create = "45m"
+  }
+}
@@ -0,0 +1 @@
+terraform import gitops_manifest.cache man-123
@@ -0,0 +1,17 @@
+resource "gitops_manifest" "cache" {
+  service_type = "redis"
+  spec = jsonencode({
+    replicas = 3
+    version  = "7.2.*"
+    storage = {
+      size = "10Gi"
+    }
+  })
+
+  target_stages  = ["deployed"]
+  failure_stages = ["failed"]
+
+  timeouts = {
+    create = "45m"
+  }
+}
@@ -0,0 +1,844 @@
+package provider
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chillout2k/gitopsclient"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testSigningKey signs the access tokens of the test API stand-ins.
+var testSigningKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+// testJwks serves the public part of testSigningKey as JWKS.
+func testJwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(testSigningKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(testSigningKey.E)).Bytes()),
+		}},
+	})
+}
+
+// signTestToken returns an access token for subject expiring after ttl,
+// signed with testSigningKey.
+func signTestToken(subject string, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": "https://idp.example.com",
+		"sub": subject,
+		"exp": time.Now().Add(ttl).Unix(),
+	})
+	token.Header["kid"] = "test"
+	return token.SignedString(testSigningKey)
+}
+
+// testAccessToken returns a signed access token expiring after ttl.
+func testAccessToken(t *testing.T, ttl time.Duration) string {
+	t.Helper()
+	signed, err := signTestToken("terraform", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// writeJSON answers with v encoded as JSON.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONStatus answers with status and v encoded as JSON.
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Credentials accepted by the token endpoint of fakeGitopsApi.
+const (
+	fakeClientId     = "terraform"
+	fakeClientSecret = "client-secret"
+	fakeUsername     = "terraform"
+	fakePassword     = "secret"
+)
+
+// fakeServices is the catalog of fakeGitopsApi. Service 42 is ordered by
+// the instance tests.
+var fakeServices = []gitopsApiService{
+	{
+		Service_id:        42,
+		Name:              "redis",
+		Description:       "Redis in-memory data store",
+		Versions:          []string{"3.2.0", "3.2.1", "3.3.0"},
+		Min_replica_count: 1,
+		Max_replica_count: 10,
+		Required_fields:   []string{"some_value"},
+	},
+	{
+		Service_id:        43,
+		Name:              "postgres",
+		Description:       "PostgreSQL database",
+		Versions:          []string{"15.4.0", "16.1.0"},
+		Min_replica_count: 1,
+		Max_replica_count: 3,
+		Required_fields:   []string{},
+	},
+}
+
+// fakeGitopsApi is an in-process stand-in for the Gitops API and its
+// OAuth2 identity provider. It issues RS256 signed access tokens on
+// /token, serves their keys on /certs and only answers instance requests
+// bearing a valid access token. Orders and updates of instances are
+// recorded in their history with the subject of the access token as actor.
+//
+// Ordered and updated instances are in stage "deploying" for the next
+// rolloutReads reads and then move on to rolloutStage. The same applies
+// to manifests, which are "syncing" until they are rolled out.
+type fakeGitopsApi struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	instances    map[string]*gitopsApiInstance
+	manifests    map[string]*gitopsApiManifest
+	history      map[string][]instanceChange
+	services     []gitopsApiService
+	pendingReads map[string]int
+	orders       map[string]string
+	nextId       int
+	failures     []fakeFailure
+	tokensIssued map[string]int
+	// refreshTokens maps the issued refresh tokens to their subject
+	refreshTokens map[string]string
+
+	// environments maps the instances to the environment they were
+	// ordered for
+	environments map[string]string
+
+	rolloutReads int
+	rolloutStage string
+	// pinnedVersions lets updates keep the version an instance runs,
+	// like a Gitops API that does not re-resolve version constraints
+	pinnedVersions bool
+	accessTokenTTL time.Duration
+	// environment is reported on /info, orders for other environments
+	// are rejected
+	environment string
+}
+
+// fakeFailure is an injected failure of the next request to method and path.
+type fakeFailure struct {
+	method string
+	path   string
+	status int
+	body   any
+	// drop processes the request but closes the connection instead of
+	// answering, like a response lost on the way back.
+	drop bool
+	// process processes the request but answers with status and body,
+	// like a server failing after it placed an order.
+	process bool
+}
+
+// newFakeGitopsApi starts a fake Gitops API, stopped at the end of the test.
+func newFakeGitopsApi(t *testing.T) *fakeGitopsApi {
+	t.Helper()
+	api := &fakeGitopsApi{
+		instances:      map[string]*gitopsApiInstance{},
+		manifests:      map[string]*gitopsApiManifest{},
+		history:        map[string][]instanceChange{},
+		services:       slices.Clone(fakeServices),
+		pendingReads:   map[string]int{},
+		orders:         map[string]string{},
+		tokensIssued:   map[string]int{},
+		refreshTokens:  map[string]string{},
+		environments:   map[string]string{},
+		rolloutReads:   1,
+		rolloutStage:   "deployed",
+		accessTokenTTL: time.Hour,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /certs", testJwks)
+	mux.HandleFunc("POST /token", api.token)
+	mux.HandleFunc("GET /info", api.authorized(api.info))
+	mux.HandleFunc("GET /instances", api.authorized(api.listInstances))
+	mux.HandleFunc("POST /instances", api.authorized(api.orderInstance))
+	mux.HandleFunc("GET /instances/{id}", api.authorized(api.getInstance))
+	mux.HandleFunc("PUT /instances/{id}", api.authorized(api.updateInstance))
+	mux.HandleFunc("DELETE /instances/{id}", api.authorized(api.deleteInstance))
+	mux.HandleFunc("GET /instances/{id}/history", api.authorized(api.getInstanceHistory))
+	mux.HandleFunc("GET /services", api.authorized(api.listServices))
+	mux.HandleFunc("GET /services/{id}", api.authorized(api.getService))
+	mux.HandleFunc("GET /manifests", api.authorized(api.listManifests))
+	mux.HandleFunc("POST /manifests", api.authorized(api.orderManifest))
+	mux.HandleFunc("GET /manifests/{id}", api.authorized(api.getManifest))
+	mux.HandleFunc("PUT /manifests/{id}", api.authorized(api.updateManifest))
+	mux.HandleFunc("DELETE /manifests/{id}", api.authorized(api.deleteManifest))
+
+	api.Server = httptest.NewServer(api.inject(mux))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// failNext lets the next request to method and path fail with status and
+// body encoded as JSON.
+func (api *fakeGitopsApi) failNext(method string, path string, status int, body any) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.failures = append(api.failures, fakeFailure{method: method, path: path, status: status, body: body})
+}
+
+// failNextAfterProcessing processes the next request to method and path,
+// but lets it fail with status and body encoded as JSON.
+func (api *fakeGitopsApi) failNextAfterProcessing(method string, path string, status int, body any) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.failures = append(api.failures, fakeFailure{method: method, path: path, status: status, body: body, process: true})
+}
+
+// dropNext processes the next request to method and path, but drops its
+// response.
+func (api *fakeGitopsApi) dropNext(method string, path string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.failures = append(api.failures, fakeFailure{method: method, path: path, drop: true})
+}
+
+// inject applies the injected failures.
+func (api *fakeGitopsApi) inject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		var failure *fakeFailure
+		for i, f := range api.failures {
+			if f.method == r.Method && f.path == r.URL.Path {
+				failure = &f
+				api.failures = append(api.failures[:i], api.failures[i+1:]...)
+				break
+			}
+		}
+		api.mu.Unlock()
+
+		switch {
+		case failure == nil:
+			next.ServeHTTP(w, r)
+		case failure.drop:
+			next.ServeHTTP(httptest.NewRecorder(), r)
+			// The response breaks off after its status line, so the
+			// transport of the client does not resend the request on its own
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				buf.WriteString("HTTP/1.1 200 OK\r\n")
+				buf.Flush()
+				conn.Close()
+			}
+		default:
+			if failure.process {
+				next.ServeHTTP(httptest.NewRecorder(), r)
+			}
+			writeJSONStatus(w, failure.status, failure.body)
+		}
+	})
+}
+
+// token implements the resource owner password credentials, the client
+// credentials, the token exchange, the JWT bearer and the refresh token
+// grant. ID tokens have to be signed by testSigningKey, their subject
+// becomes the subject of the access token.
+func (api *fakeGitopsApi) token(w http.ResponseWriter, r *http.Request) {
+	var subject, refreshToken string
+	switch r.FormValue("grant_type") {
+	case "refresh_token":
+		api.mu.Lock()
+		refreshedSubject, ok := api.refreshTokens[r.FormValue("refresh_token")]
+		delete(api.refreshTokens, r.FormValue("refresh_token"))
+		api.mu.Unlock()
+		if !ok || r.FormValue("client_id") != fakeClientId {
+			writeJSONStatus(w, http.StatusBadRequest, map[string]string{
+				"error":             "invalid_grant",
+				"error_description": "Invalid refresh token",
+			})
+			return
+		}
+		// Refresh tokens are rotated
+		subject, refreshToken = refreshedSubject, newTestId()
+	case "password":
+		if r.FormValue("client_id") != fakeClientId ||
+			r.FormValue("username") != fakeUsername || r.FormValue("password") != fakePassword {
+			writeJSONStatus(w, http.StatusUnauthorized, map[string]string{
+				"error":             "invalid_grant",
+				"error_description": "Invalid user credentials",
+			})
+			return
+		}
+		subject, refreshToken = r.FormValue("username"), newTestId()
+	case "client_credentials":
+		if r.FormValue("client_id") != fakeClientId || r.FormValue("client_secret") != fakeClientSecret {
+			writeJSONStatus(w, http.StatusUnauthorized, map[string]string{
+				"error":             "invalid_client",
+				"error_description": "Invalid client or Invalid client credentials",
+			})
+			return
+		}
+		subject = "service-account-" + r.FormValue("client_id")
+	case "urn:ietf:params:oauth:grant-type:token-exchange", "urn:ietf:params:oauth:grant-type:jwt-bearer":
+		idToken := r.FormValue("assertion")
+		if r.FormValue("grant_type") == "urn:ietf:params:oauth:grant-type:token-exchange" {
+			idToken = r.FormValue("subject_token")
+			if r.FormValue("subject_token_type") != "urn:ietf:params:oauth:token-type:id_token" {
+				writeJSONStatus(w, http.StatusBadRequest, map[string]string{
+					"error":             "invalid_request",
+					"error_description": "Unsupported subject_token_type " + r.FormValue("subject_token_type"),
+				})
+				return
+			}
+		}
+		claims, err := verifyTestToken(idToken)
+		if err != nil || r.FormValue("client_id") != fakeClientId {
+			writeJSONStatus(w, http.StatusUnauthorized, map[string]string{
+				"error":             "invalid_grant",
+				"error_description": "Invalid ID token",
+			})
+			return
+		}
+		subject, _ = claims.GetSubject()
+	default:
+		writeJSONStatus(w, http.StatusBadRequest, map[string]string{
+			"error":             "unsupported_grant_type",
+			"error_description": "Unsupported grant_type " + r.FormValue("grant_type"),
+		})
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	accessToken, err := signTestToken(subject, api.accessTokenTTL)
+	if err != nil {
+		writeJSONStatus(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	api.tokensIssued[r.FormValue("grant_type")]++
+	if refreshToken != "" {
+		api.refreshTokens[refreshToken] = subject
+	}
+	writeJSON(w, map[string]any{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(api.accessTokenTTL.Seconds()),
+	})
+}
+
+// authorized only passes requests with a valid access token on to next.
+func (api *fakeGitopsApi) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenString, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found {
+			writeJSONStatus(w, http.StatusUnauthorized, map[string]string{"detail": "Not authenticated"})
+			return
+		}
+		if _, err := verifyTestToken(tokenString); err != nil {
+			writeJSONStatus(w, http.StatusUnauthorized, map[string]string{"detail": "Invalid token: " + err.Error()})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// verifyTestToken verifies a token signed with testSigningKey.
+func verifyTestToken(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (any, error) {
+		return &testSigningKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithExpirationRequired())
+	return claims, err
+}
+
+func (api *fakeGitopsApi) info(w http.ResponseWriter, _ *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	writeJSON(w, gitopsApiInfo{Environment: api.environment})
+}
+
+func (api *fakeGitopsApi) listInstances(w http.ResponseWriter, _ *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	instanceIds := make([]string, 0, len(api.instances))
+	for instanceId := range api.instances {
+		instanceIds = append(instanceIds, instanceId)
+	}
+	writeJSON(w, instanceIds)
+}
+
+func (api *fakeGitopsApi) orderInstance(w http.ResponseWriter, r *http.Request) {
+	var order instanceOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		writeJSONStatus(w, http.StatusUnprocessableEntity, map[string]string{"detail": err.Error()})
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if order.Environment != "" && order.Environment != api.environment {
+		writeJSONStatus(w, http.StatusUnprocessableEntity, map[string]string{
+			"detail": "Order for environment " + order.Environment + " sent to environment " + api.environment,
+		})
+		return
+	}
+
+	// Repeated orders return the instance of the first one
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if instanceId, ok := api.orders[idempotencyKey]; ok && idempotencyKey != "" {
+		if instance, ok := api.instances[instanceId]; ok {
+			writeJSONStatus(w, http.StatusCreated, instance)
+			return
+		}
+	}
+
+	api.nextId++
+	instance := &gitopsApiInstance{
+		Instance: gitopsclient.Instance{
+			Instance_id:   fmt.Sprintf("inst-%d", api.nextId),
+			Order_time:    time.Now().UTC().Format(time.RFC3339),
+			Stage:         "deploying",
+			Instance_name: order.Instance_name,
+			Orderer_id:    order.Orderer_id,
+			Bits_account:  order.Bits_account,
+			Service_id:    order.Service_id,
+			Replica_count: order.Replica_count,
+			Version:       order.Version,
+			Some_value:    order.Some_value,
+		},
+	}
+	if order.Metadata != nil {
+		instance.Metadata = *order.Metadata
+	}
+	api.instances[instance.Instance_id] = instance
+	api.pendingReads[instance.Instance_id] = api.rolloutReads
+	api.recordChange(r, instance.Instance_id, "", changedInstanceFields(gitopsApiInstance{}, *instance))
+	api.environments[instance.Instance_id] = order.Environment
+	if idempotencyKey != "" {
+		api.orders[idempotencyKey] = instance.Instance_id
+	}
+	writeJSONStatus(w, http.StatusCreated, instance)
+}
+
+func (api *fakeGitopsApi) getInstance(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	instance, ok := api.instances[r.PathValue("id")]
+	if !ok {
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Instance not found"})
+		return
+	}
+	if pending, ok := api.pendingReads[instance.Instance_id]; ok {
+		if pending == 0 {
+			api.recordStageTransition(instance.Instance_id, instance.Stage, api.rolloutStage)
+			instance.Stage = api.rolloutStage
+			delete(api.pendingReads, instance.Instance_id)
+		} else {
+			api.pendingReads[instance.Instance_id] = pending - 1
+		}
+	}
+	writeJSON(w, instance)
+}
+
+func (api *fakeGitopsApi) updateInstance(w http.ResponseWriter, r *http.Request) {
+	var update instanceUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeJSONStatus(w, http.StatusUnprocessableEntity, map[string]string{"detail": err.Error()})
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	instance, ok := api.instances[r.PathValue("id")]
+	if !ok {
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Instance not found"})
+		return
+	}
+	previous := *instance
+	instance.Instance_name = update.Instance_name
+	instance.Bits_account = update.Bits_account
+	instance.Service_id = update.Service_id
+	instance.Replica_count = update.Replica_count
+	if !api.pinnedVersions {
+		instance.Version = update.Version
+	}
+	instance.Some_value = update.Some_value
+	instance.Metadata = update.Metadata
+	instance.Stage = "deploying"
+	api.pendingReads[instance.Instance_id] = api.rolloutReads
+	api.recordChange(r, instance.Instance_id, previous.Stage, changedInstanceFields(previous, *instance))
+	writeJSON(w, instance)
+}
+
+func (api *fakeGitopsApi) getInstanceHistory(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if _, ok := api.instances[r.PathValue("id")]; !ok {
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Instance not found"})
+		return
+	}
+	writeJSON(w, api.history[r.PathValue("id")])
+}
+
+// recordChange appends a change of changedFields to the history of
+// instanceId, moving it from fromStage to "deploying". The caller must
+// hold api.mu.
+func (api *fakeGitopsApi) recordChange(r *http.Request, instanceId string, fromStage string, changedFields []string) {
+	tokenString, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	claims, _ := verifyTestToken(tokenString)
+	actor, _ := claims.GetSubject()
+	commitSha := make([]byte, 20)
+	rand.Read(commitSha)
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	api.history[instanceId] = append(api.history[instanceId], instanceChange{
+		Timestamp:      now,
+		Actor:          actor,
+		Changed_fields: changedFields,
+		Commit_sha:     hex.EncodeToString(commitSha),
+		Stage_transitions: []stageTransition{
+			{Timestamp: now, From_stage: fromStage, To_stage: "deploying"},
+		},
+	})
+}
+
+// recordStageTransition adds a transition of instanceId from fromStage to
+// toStage to its latest change. The caller must hold api.mu.
+func (api *fakeGitopsApi) recordStageTransition(instanceId string, fromStage string, toStage string) {
+	changes := api.history[instanceId]
+	if len(changes) == 0 {
+		return
+	}
+	latest := &changes[len(changes)-1]
+	latest.Stage_transitions = append(latest.Stage_transitions, stageTransition{
+		Timestamp:  time.Now().UTC().Format(time.RFC3339Nano),
+		From_stage: fromStage,
+		To_stage:   toStage,
+	})
+}
+
+// changedInstanceFields returns the fields that differ between previous
+// and current, by their API names.
+func changedInstanceFields(previous gitopsApiInstance, current gitopsApiInstance) []string {
+	fields := []struct {
+		name    string
+		changed bool
+	}{
+		{"instance_name", previous.Instance_name != current.Instance_name},
+		{"orderer_id", previous.Orderer_id != current.Orderer_id},
+		{"bits_account", previous.Bits_account != current.Bits_account},
+		{"service_id", previous.Service_id != current.Service_id},
+		{"replica_count", previous.Replica_count != current.Replica_count},
+		{"version", previous.Version != current.Version},
+		{"some_value", previous.Some_value != current.Some_value},
+		{"metadata", !maps.Equal(previous.Metadata.Labels, current.Metadata.Labels)},
+	}
+	changedFields := []string{}
+	for _, field := range fields {
+		if field.changed {
+			changedFields = append(changedFields, field.name)
+		}
+	}
+	return changedFields
+}
+
+func (api *fakeGitopsApi) deleteInstance(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if _, ok := api.instances[r.PathValue("id")]; !ok {
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Instance not found"})
+		return
+	}
+	delete(api.instances, r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *fakeGitopsApi) listServices(w http.ResponseWriter, _ *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	writeJSON(w, api.services)
+}
+
+func (api *fakeGitopsApi) getService(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	for _, service := range api.services {
+		if strconv.FormatUint(service.Service_id, 10) == r.PathValue("id") {
+			writeJSON(w, service)
+			return
+		}
+	}
+	writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Service not found"})
+}
+
+func (api *fakeGitopsApi) listManifests(w http.ResponseWriter, _ *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	manifestIds := make([]string, 0, len(api.manifests))
+	for manifestId := range api.manifests {
+		manifestIds = append(manifestIds, manifestId)
+	}
+	writeJSON(w, manifestIds)
+}
+
+func (api *fakeGitopsApi) orderManifest(w http.ResponseWriter, r *http.Request) {
+	var order manifestOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		writeJSONStatus(w, http.StatusUnprocessableEntity, map[string]string{"detail": err.Error()})
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if order.Environment != "" && order.Environment != api.environment {
+		writeJSONStatus(w, http.StatusUnprocessableEntity, map[string]string{
+			"detail": "Order for environment " + order.Environment + " sent to environment " + api.environment,
+		})
+		return
+	}
+
+	// Repeated orders return the manifest of the first one
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if manifestId, ok := api.orders[idempotencyKey]; ok && idempotencyKey != "" {
+		if manifest, ok := api.manifests[manifestId]; ok {
+			writeJSONStatus(w, http.StatusCreated, manifest)
+			return
+		}
+	}
+
+	api.nextId++
+	manifest := &gitopsApiManifest{
+		Manifest_id:  fmt.Sprintf("man-%d", api.nextId),
+		Order_time:   time.Now().UTC().Format(time.RFC3339),
+		Service_type: order.Service_type,
+		Spec:         compactJSON(order.Spec),
+		Stage:        "deploying",
+		Status:       "syncing",
+	}
+	api.manifests[manifest.Manifest_id] = manifest
+	api.pendingReads[manifest.Manifest_id] = api.rolloutReads
+	if idempotencyKey != "" {
+		api.orders[idempotencyKey] = manifest.Manifest_id
+	}
+	writeJSONStatus(w, http.StatusCreated, manifest)
+}
+
+func (api *fakeGitopsApi) getManifest(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	manifest, ok := api.manifests[r.PathValue("id")]
+	if !ok {
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Manifest not found"})
+		return
+	}
+	if pending, ok := api.pendingReads[manifest.Manifest_id]; ok {
+		if pending == 0 {
+			manifest.Stage = api.rolloutStage
+			manifest.Status = "synced"
+			delete(api.pendingReads, manifest.Manifest_id)
+		} else {
+			api.pendingReads[manifest.Manifest_id] = pending - 1
+		}
+	}
+	writeJSON(w, manifest)
+}
+
+func (api *fakeGitopsApi) updateManifest(w http.ResponseWriter, r *http.Request) {
+	var update manifestUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeJSONStatus(w, http.StatusUnprocessableEntity, map[string]string{"detail": err.Error()})
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	manifest, ok := api.manifests[r.PathValue("id")]
+	if !ok {
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Manifest not found"})
+		return
+	}
+	manifest.Spec = compactJSON(update.Spec)
+	manifest.Stage = "deploying"
+	manifest.Status = "syncing"
+	api.pendingReads[manifest.Manifest_id] = api.rolloutReads
+	writeJSON(w, manifest)
+}
+
+func (api *fakeGitopsApi) deleteManifest(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if _, ok := api.manifests[r.PathValue("id")]; !ok {
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Manifest not found"})
+		return
+	}
+	delete(api.manifests, r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// compactJSON normalizes spec like a real API storing the decoded object
+// would, so the provider has to cope with reformatted specs.
+func compactJSON(spec json.RawMessage) json.RawMessage {
+	var object any
+	if err := json.Unmarshal(spec, &object); err != nil {
+		return spec
+	}
+	compacted, _ := json.Marshal(object)
+	return compacted
+}
+
+// instance returns a copy of the instance with instanceId.
+func (api *fakeGitopsApi) instance(instanceId string) (gitopsApiInstance, bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	instance, ok := api.instances[instanceId]
+	if !ok {
+		return gitopsApiInstance{}, false
+	}
+	return *instance, true
+}
+
+// orderedEnvironment returns the environment instanceId was ordered for.
+func (api *fakeGitopsApi) orderedEnvironment(instanceId string) string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.environments[instanceId]
+}
+
+// setAccessTokenTTL changes the lifetime of access tokens issued by api.
+func (api *fakeGitopsApi) setAccessTokenTTL(ttl time.Duration) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.accessTokenTTL = ttl
+}
+
+// issuedTokens returns the number of access tokens issued so far.
+func (api *fakeGitopsApi) issuedTokens() int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	issued := 0
+	for _, count := range api.tokensIssued {
+		issued += count
+	}
+	return issued
+}
+
+// refreshedTokens returns the number of access tokens issued by the
+// refresh token grant so far.
+func (api *fakeGitopsApi) refreshedTokens() int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.tokensIssued["refresh_token"]
+}
+
+// instanceCount returns the number of existing instances.
+func (api *fakeGitopsApi) instanceCount() int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return len(api.instances)
+}
+
+// modifyInstance changes an instance out of band.
+func (api *fakeGitopsApi) modifyInstance(instanceId string, modify func(*gitopsApiInstance)) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if instance, ok := api.instances[instanceId]; ok {
+		modify(instance)
+	}
+}
+
+// pinVersions lets updates keep the version instances run.
+func (api *fakeGitopsApi) pinVersions() {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.pinnedVersions = true
+}
+
+// setServiceVersions changes the versions of a catalog service.
+func (api *fakeGitopsApi) setServiceVersions(serviceId uint64, versions ...string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	for i := range api.services {
+		if api.services[i].Service_id == serviceId {
+			api.services[i].Versions = versions
+		}
+	}
+}
+
+// addService adds a service to the catalog.
+func (api *fakeGitopsApi) addService(service gitopsApiService) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.services = append(api.services, service)
+}
+
+// removeService drops a service from the catalog.
+func (api *fakeGitopsApi) removeService(serviceId uint64) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.services = slices.DeleteFunc(api.services, func(service gitopsApiService) bool {
+		return service.Service_id == serviceId
+	})
+}
+
+// manifest returns a copy of the manifest with manifestId.
+func (api *fakeGitopsApi) manifest(manifestId string) (gitopsApiManifest, bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	manifest, ok := api.manifests[manifestId]
+	if !ok {
+		return gitopsApiManifest{}, false
+	}
+	return *manifest, true
+}
+
+// manifestCount returns the number of existing manifests.
+func (api *fakeGitopsApi) manifestCount() int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return len(api.manifests)
+}
+
+// modifyManifest changes a manifest out of band.
+func (api *fakeGitopsApi) modifyManifest(manifestId string, modify func(*gitopsApiManifest)) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if manifest, ok := api.manifests[manifestId]; ok {
+		modify(manifest)
+	}
+}
+
+// removeInstance deletes an instance out of band.
+func (api *fakeGitopsApi) removeInstance(instanceId string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	delete(api.instances, instanceId)
+}
+
+// newTestId returns a random hex ID.
+func newTestId() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package provider
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile blocks until it holds an exclusive lock of f.
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the lock of f.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,239 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// serviceCatalog caches the services of the catalog looked up while
+// planning, so each service is fetched once per provider run however many
+// instances order it.
+type serviceCatalog struct {
+	mu       sync.Mutex
+	services map[uint64]*catalogLookup
+}
+
+// catalogLookup is a lookup of a service, done once closed.
+type catalogLookup struct {
+	done    chan struct{}
+	service gitopsApiService
+	found   bool
+	err     error
+}
+
+// catalogService returns the catalog service with service_id and whether
+// it exists. Concurrent lookups of a service share a single request. Only
+// found services are cached, a missing service may be added to the
+// catalog while the provider runs.
+func (c *gitopsApiClient) catalogService(ctx context.Context, service_id uint64) (gitopsApiService, bool, error) {
+	for {
+		c.catalog.mu.Lock()
+		lookup, ok := c.catalog.services[service_id]
+		if !ok {
+			if c.catalog.services == nil {
+				c.catalog.services = map[uint64]*catalogLookup{}
+			}
+			lookup = &catalogLookup{done: make(chan struct{})}
+			c.catalog.services[service_id] = lookup
+		}
+		c.catalog.mu.Unlock()
+
+		if !ok {
+			return c.lookupCatalogService(ctx, service_id, lookup)
+		}
+		select {
+		case <-lookup.done:
+		case <-ctx.Done():
+			return gitopsApiService{}, false, ctx.Err()
+		}
+		// The failure may be specific to the context of the other lookup
+		if lookup.err == nil {
+			return lookup.service, lookup.found, nil
+		}
+	}
+}
+
+// lookupCatalogService fetches the service with service_id for lookup
+// and forgets lookup unless the service was found.
+func (c *gitopsApiClient) lookupCatalogService(ctx context.Context, service_id uint64, lookup *catalogLookup) (gitopsApiService, bool, error) {
+	service, err := c.GetService(ctx, service_id)
+	switch {
+	case err == nil:
+		lookup.service, lookup.found = service, true
+	case !isNotFound(err):
+		lookup.err = err
+	}
+	if !lookup.found {
+		c.catalog.mu.Lock()
+		delete(c.catalog.services, service_id)
+		c.catalog.mu.Unlock()
+	}
+	close(lookup.done)
+	return lookup.service, lookup.found, lookup.err
+}
+
+// catalogVersion returns the highest version of the catalog service with
+// service_id satisfying constraint, and whether there is one.
+func (c *gitopsApiClient) catalogVersion(ctx context.Context, service_id uint64, constraint string) (string, bool, error) {
+	parsedConstraint, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return "", false, err
+	}
+	service, found, err := c.catalogService(ctx, service_id)
+	if err != nil || !found {
+		return "", false, err
+	}
+	return resolveVersion(parsedConstraint, service.Versions)
+}
+
+// resolvedVersion returns the concrete version of instance: the version
+// reported by the Gitops API if it is concrete, otherwise the current
+// resolved version while it satisfies the reported constraint, as the
+// instance is only rolled out to newer versions by updates, and finally
+// the highest version of its catalog service satisfying the constraint.
+func (c *gitopsApiClient) resolvedVersion(ctx context.Context, instance gitopsApiInstance, current types.String) types.String {
+	if _, err := parseVersion(instance.Version); err == nil {
+		return types.StringValue(instance.Version)
+	}
+	if constraint, err := parseVersionConstraint(instance.Version); err == nil && !current.IsNull() && !current.IsUnknown() {
+		if version, err := parseVersion(current.ValueString()); err == nil && constraint.Check(version) {
+			return current
+		}
+	}
+	resolved, ok, err := c.catalogVersion(ctx, instance.Service_id, instance.Version)
+	if err != nil {
+		tflog.Warn(ctx, "Could not resolve gitops instance version from the catalog", map[string]any{
+			"instance_id": instance.Instance_id,
+			"version":     instance.Version,
+			"error":       err.Error(),
+		})
+	}
+	if !ok {
+		return types.StringNull()
+	}
+	return types.StringValue(resolved)
+}
+
+// requiredInstanceStringAttributes are the instance attributes a service
+// may require in required_fields that can be set to an empty value.
+var requiredInstanceStringAttributes = []string{
+	"instance_name",
+	"orderer_id",
+	"some_value",
+}
+
+// checkCatalogConstraints checks the planned service_id, replica_count,
+// version and the fields required by the service against the catalog.
+// Only attributes changed since the last apply are checked, so existing
+// instances keep planning cleanly after the catalog drops their version
+// or their service. The catalog is not read if nothing changed.
+// Unknown values are checked once they are known during apply.
+func (r *gitopsInstanceResource) checkCatalogConstraints(ctx context.Context, req resource.ModifyPlanRequest) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var plan, state gitopsInstanceResourceModel
+	diags.Append(req.Plan.Get(ctx, &plan)...)
+	if !req.State.Raw.IsNull() {
+		diags.Append(req.State.Get(ctx, &state)...)
+	}
+	if diags.HasError() || plan.Service_id.IsUnknown() {
+		return diags
+	}
+
+	serviceChanged := !plan.Service_id.Equal(state.Service_id)
+	checkReplicaCount := !plan.Replica_count.IsUnknown() && (serviceChanged || !plan.Replica_count.Equal(state.Replica_count))
+	checkVersion := !plan.Version.IsUnknown() && (serviceChanged || !plan.Version.Equal(state.Version))
+	requiredFieldChanged := false
+	for _, field := range requiredInstanceStringAttributes {
+		var planned, current types.String
+		diags.Append(req.Plan.GetAttribute(ctx, path.Root(field), &planned)...)
+		if !req.State.Raw.IsNull() {
+			diags.Append(req.State.GetAttribute(ctx, path.Root(field), &current)...)
+		}
+		requiredFieldChanged = requiredFieldChanged || !planned.Equal(current)
+	}
+	if diags.HasError() || !(serviceChanged || checkReplicaCount || checkVersion || requiredFieldChanged) {
+		return diags
+	}
+
+	serviceId := plan.Service_id.ValueInt64()
+	service, found, err := r.client.catalogService(ctx, uint64(serviceId))
+	if err != nil {
+		// The Gitops API validates the order anyway, do not fail the plan
+		// because the catalog cannot be read
+		diags.AddWarning(
+			"Unable to Check gitopsInstance Against the Catalog",
+			fmt.Sprintf("Could not read service %d from the Gitops API catalog, the instance is only validated on apply: %s", serviceId, err),
+		)
+		return diags
+	}
+	if !found {
+		// Existing instances of a service dropped from the catalog can
+		// still be refreshed and destroyed
+		if !serviceChanged {
+			return diags
+		}
+		diags.AddAttributeError(
+			path.Root("service_id"),
+			"Unknown Gitops Service",
+			fmt.Sprintf("Service %d does not exist in the Gitops API catalog. "+
+				"Look up the available services with the gitops_services data source.", serviceId),
+		)
+		return diags
+	}
+
+	if checkReplicaCount {
+		replicaCount := plan.Replica_count.ValueInt64()
+		if replicaCount < int64(service.Min_replica_count) ||
+			(service.Max_replica_count > 0 && replicaCount > int64(service.Max_replica_count)) {
+			diags.AddAttributeError(
+				path.Root("replica_count"),
+				"Invalid Replica Count",
+				fmt.Sprintf("Service %s allows between %d and %d replicas, got: %d.",
+					service.Name, service.Min_replica_count, service.Max_replica_count, replicaCount),
+			)
+		}
+	}
+
+	if checkVersion && len(service.Versions) > 0 {
+		// Malformed constraints are reported by the version validator
+		constraint, err := parseVersionConstraint(plan.Version.ValueString())
+		if err == nil {
+			if _, ok, _ := resolveVersion(constraint, service.Versions); !ok {
+				diags.AddAttributeError(
+					path.Root("version"),
+					"Unavailable Version",
+					fmt.Sprintf("No available version of service %s satisfies %q. Available versions: %s.",
+						service.Name, plan.Version.ValueString(), strings.Join(service.Versions, ", ")),
+				)
+			}
+		}
+	}
+
+	for _, field := range service.Required_fields {
+		if !slices.Contains(requiredInstanceStringAttributes, field) {
+			continue
+		}
+		var planned, current types.String
+		diags.Append(req.Plan.GetAttribute(ctx, path.Root(field), &planned)...)
+		if !req.State.Raw.IsNull() {
+			diags.Append(req.State.GetAttribute(ctx, path.Root(field), &current)...)
+		}
+		if (serviceChanged || !planned.Equal(current)) && !planned.IsUnknown() && planned.ValueString() == "" {
+			diags.AddAttributeError(
+				path.Root(field),
+				"Missing Required Field",
+				fmt.Sprintf("Service %s requires %s to be set.", service.Name, field),
+			)
+		}
+	}
+	return diags
+}
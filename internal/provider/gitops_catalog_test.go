@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestCatalogServiceIsCached(t *testing.T) {
+	var requests atomic.Int32
+	var fail atomic.Bool
+	client := newTestApiClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch {
+		case fail.Load():
+			writeJSONStatus(w, http.StatusForbidden, map[string]string{"detail": "Not allowed"})
+		case r.URL.Path == "/services/42":
+			writeJSON(w, fakeServices[0])
+		default:
+			writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Service not found"})
+		}
+	}), testRetryPolicy)
+	ctx := context.Background()
+
+	// Failed lookups are not cached
+	fail.Store(true)
+	if _, _, err := client.catalogService(ctx, 42); err == nil {
+		t.Fatal("expected an error")
+	}
+	fail.Store(false)
+
+	for range 2 {
+		service, found, err := client.catalogService(ctx, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || service.Name != "redis" {
+			t.Errorf("got service %+v, found %t, want redis", service, found)
+		}
+		if _, found, err := client.catalogService(ctx, 1); err != nil || found {
+			t.Errorf("got found %t, error %v for a missing service", found, err)
+		}
+	}
+	// Missing services are looked up again, they may have been added
+	if got := requests.Load(); got != 4 {
+		t.Errorf("got %d requests, want the found service looked up once after the failure", got)
+	}
+}
+
+func TestCatalogServiceSharesConcurrentLookups(t *testing.T) {
+	var requests atomic.Int32
+	fetching, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	client := newTestApiClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/services/42" {
+			once.Do(func() { close(fetching) })
+			<-release
+			writeJSON(w, fakeServices[0])
+			return
+		}
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Service not found"})
+	}), testRetryPolicy)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if service, found, err := client.catalogService(ctx, 42); err != nil || !found || service.Name != "redis" {
+				t.Errorf("got service %+v, found %t, error %v, want redis", service, found, err)
+			}
+		}()
+	}
+
+	// Other services are looked up while service 42 is being fetched
+	<-fetching
+	if _, found, err := client.catalogService(ctx, 1); err != nil || found {
+		t.Errorf("got found %t, error %v for a missing service", found, err)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d requests, want one per service", got)
+	}
+}
+
+func TestAccGitopsInstanceResourceCatalogConstraints(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir())
+	instanceConfig := testAccInstanceConfig("test-instance", 1)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config:      config + testAccInstanceConfig("test-instance", 11),
+				ExpectError: regexp.MustCompile(`(?s)Invalid Replica Count.*Service redis allows between 1 and 10\s+replicas, got: 11`),
+			},
+			{
+				Config:      config + strings.Replace(instanceConfig, `"3.2.1"`, `"4.*"`, 1),
+				ExpectError: regexp.MustCompile(`(?s)Unavailable Version.*3.2.0, 3.2.1, 3.3.0`),
+			},
+			{
+				Config:      config + strings.Replace(instanceConfig, "service_id    = 42", "service_id    = 99", 1),
+				ExpectError: regexp.MustCompile(`Unknown Gitops Service`),
+			},
+			{
+				Config:      config + strings.Replace(instanceConfig, `"some value"`, `""`, 1),
+				ExpectError: regexp.MustCompile(`(?s)Missing Required Field.*requires some_value`),
+			},
+			{
+				Config: config + strings.Replace(instanceConfig, `"3.2.1"`, `"3.3.*"`, 1),
+			},
+			// Instances keep planning after the catalog dropped their version
+			{
+				PreConfig: func() {
+					api.setServiceVersions(42, "3.4.0")
+				},
+				Config:   config + strings.Replace(instanceConfig, `"3.2.1"`, `"3.3.*"`, 1),
+				PlanOnly: true,
+			},
+			{
+				Config: config + strings.Replace(testAccInstanceConfig("test-instance", 2), `"3.2.1"`, `"3.3.*"`, 1),
+			},
+			{
+				Config:      config + strings.Replace(testAccInstanceConfig("test-instance", 2), `"3.2.1"`, `"3.5.*"`, 1),
+				ExpectError: regexp.MustCompile(`Unavailable Version`),
+			},
+			{
+				Config: config + strings.Replace(testAccInstanceConfig("test-instance", 2), `"3.2.1"`, `"3.4.*"`, 1),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceRemovedCatalogService(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir())
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config: config + testAccInstanceConfig("test-instance", 1),
+			},
+			// Instances keep planning and can be destroyed after the catalog
+			// dropped their service
+			{
+				PreConfig: func() {
+					api.removeService(42)
+				},
+				Config:   config + testAccInstanceConfig("test-instance", 1),
+				PlanOnly: true,
+			},
+			{
+				Config:             config + testAccInstanceConfig("test-instance", 2),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
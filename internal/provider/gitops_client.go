@@ -0,0 +1,406 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chillout2k/gitopsclient"
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// gitopsApiClient wraps the gitops client and adds the API calls the
+// provider needs on top of what gitopsclient offers. The instance calls
+// shadow the gitopsclient ones to bind every request to a context, so
+// resource timeouts also cancel in-flight requests, and to retry
+// transient failures.
+type gitopsApiClient struct {
+	*gitopsclient.GitopsClient
+	retryPolicy gitopsRetryPolicy
+	// immutableInstanceAttributes are the instance attributes the Gitops
+	// API does not update in place, changing them replaces the instance.
+	immutableInstanceAttributes []string
+	// defaultLabels are added to the labels of every instance.
+	defaultLabels map[string]string
+	// idToken and idTokenFile hold the ID token of the workload for the
+	// token_exchange and jwt_bearer grants, see idTokenGrant.
+	idToken     string
+	idTokenFile string
+	// environment is the Gitops API environment the provider is
+	// configured for, e.g. "prod". Empty if none is configured.
+	environment string
+	// staticAccessToken is set for a pre-issued access token, which is
+	// used as is and never renewed.
+	staticAccessToken bool
+	// tokenCache shares the tokens with other provider configurations
+	// and Terraform processes using the same identity.
+	tokenCache tokenCache
+	// rejectedAccessToken is the access token last rejected by the Gitops
+	// API, which must not be adopted from the token cache again.
+	rejectedAccessToken string
+	// tokenMu serializes access token renewals, see accessToken.
+	tokenMu sync.Mutex
+	// authenticated is set once the client obtained its first access
+	// token. Interactive grants are not run again afterwards.
+	authenticated bool
+	// catalog caches the catalog services checked while planning.
+	catalog serviceCatalog
+	// ordersPath is the directory the tokens of pending orders are kept
+	// in, see orderToken. Empty if no cache_path is configured.
+	ordersPath string
+}
+
+// newGitopsApiClient wraps an already configured gitops client.
+func newGitopsApiClient(client *gitopsclient.GitopsClient, retryPolicy gitopsRetryPolicy) *gitopsApiClient {
+	return &gitopsApiClient{
+		GitopsClient: client,
+		retryPolicy:  retryPolicy,
+		tokenCache:   noTokenCache{},
+	}
+}
+
+// request prepares an API request bound to ctx, authenticated with
+// accessToken.
+func (c *gitopsApiClient) request(ctx context.Context, accessToken string) *resty.Request {
+	return c.RestyClient.R().
+		SetContext(ctx).
+		SetAuthToken(accessToken)
+}
+
+// handleResponse turns non-2xx responses into *gitopsApiError.
+func (c *gitopsApiClient) handleResponse(resp *resty.Response) error {
+	if resp.StatusCode() > 299 {
+		return newGitopsApiError(resp)
+	}
+	return nil
+}
+
+// execute sends a request to the Gitops API path uri, letting prepare
+// set body, result and headers. Transient failures are retried according
+// to the retry policy, but only if the request is retryable, i.e. sending
+// it twice has the same effect as sending it once. Requests rejected as
+// unauthenticated are sent once more with a renewed access token.
+// Failures without a response of the Gitops API are returned as
+// *gitopsRequestError once the request was sent.
+func (c *gitopsApiClient) execute(ctx context.Context, method string, uri string, retryable bool, prepare func(*resty.Request)) error {
+	ctx = c.logContext(ctx)
+	var sent atomic.Bool
+	requestCtx := httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				sent.Store(true)
+			}
+		},
+	})
+	// Once any attempt was sent, the Gitops API may have processed the
+	// request whatever stops the following attempts
+	sentError := func(err error) error {
+		if sent.Load() {
+			return &gitopsRequestError{Err: err}
+		}
+		return err
+	}
+	reauthenticated := false
+	for attempt := 0; ; attempt++ {
+		accessToken, err := c.accessToken(ctx)
+		if err != nil {
+			return sentError(err)
+		}
+		req := c.request(requestCtx, accessToken)
+		prepare(req)
+		resp, err := req.Execute(method, c.GitopsApiURI+uri)
+		if err == nil && resp.StatusCode() == http.StatusUnauthorized && !reauthenticated && !c.staticAccessToken {
+			tflog.Debug(ctx, "Gitops API rejected the access token, renewing it", map[string]any{
+				"method": method,
+				"uri":    uri,
+			})
+			c.invalidateAccessToken(accessToken)
+			reauthenticated = true
+			attempt--
+			continue
+		}
+		if !retryable || attempt >= c.retryPolicy.MaxRetries || !c.retryPolicy.shouldRetry(ctx, resp, err) {
+			if err != nil {
+				return sentError(err)
+			}
+			return c.handleResponse(resp)
+		}
+
+		wait := c.retryPolicy.wait(attempt, resp)
+		fields := map[string]any{
+			"method":  method,
+			"uri":     uri,
+			"attempt": attempt + 1,
+			"wait":    wait.String(),
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		} else {
+			fields["status"] = resp.StatusCode()
+		}
+		tflog.Warn(ctx, "Retrying failed Gitops API request", fields)
+
+		if err := sleep(ctx, wait); err != nil {
+			return sentError(err)
+		}
+	}
+}
+
+// gitopsApiInfo describes the Gitops API.
+type gitopsApiInfo struct {
+	// Environment is the environment the Gitops API deploys to, e.g. "prod"
+	Environment string `json:"environment"`
+}
+
+// instanceMetadata is the metadata the Gitops API keeps with an instance.
+type instanceMetadata struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// gitopsApiInstance extends the gitopsclient instance with the fields of
+// newer Gitops API versions.
+type gitopsApiInstance struct {
+	gitopsclient.Instance
+	Metadata instanceMetadata `json:"metadata"`
+}
+
+// instanceOrder extends the gitopsclient order with the fields of newer
+// Gitops API versions.
+type instanceOrder struct {
+	gitopsclient.InstanceOrder
+	// Environment lets the Gitops API reject orders meant for another
+	// environment.
+	Environment string            `json:"environment,omitempty"`
+	Metadata    *instanceMetadata `json:"metadata,omitempty"`
+}
+
+// instanceUpdate extends the gitopsclient update with the fields of newer
+// Gitops API versions. The metadata replaces the current one.
+type instanceUpdate struct {
+	gitopsclient.InstanceUpdate
+	Metadata instanceMetadata `json:"metadata"`
+}
+
+// GetApiInfo returns the description of the Gitops API.
+func (c *gitopsApiClient) GetApiInfo(ctx context.Context) (gitopsApiInfo, error) {
+	var info gitopsApiInfo
+	err := c.execute(ctx, resty.MethodGet, "/info", true, func(req *resty.Request) {
+		req.SetResult(&info)
+	})
+	return info, err
+}
+
+// PostInstanceOrder orders a new instance. The order is only retried if
+// an idempotencyKey is given, which lets the Gitops API recognize
+// repeated orders.
+func (c *gitopsApiClient) PostInstanceOrder(ctx context.Context, order_request instanceOrder, idempotencyKey string) (gitopsApiInstance, error) {
+	var instance gitopsApiInstance
+	order_request.Environment = c.environment
+	err := c.execute(ctx, resty.MethodPost, "/instances", idempotencyKey != "", func(req *resty.Request) {
+		req.SetBody(order_request).SetResult(&instance)
+		if idempotencyKey != "" {
+			req.SetHeader("Idempotency-Key", idempotencyKey)
+		}
+	})
+	return instance, err
+}
+
+func (c *gitopsApiClient) GetInstance(ctx context.Context, instance_id string) (gitopsApiInstance, error) {
+	var instance gitopsApiInstance
+	err := c.execute(ctx, resty.MethodGet, "/instances/"+instance_id, true, func(req *resty.Request) {
+		req.SetResult(&instance)
+	})
+	return instance, err
+}
+
+// ListInstances returns every instance visible to the authenticated
+// principal. The instances endpoint only lists instance IDs, so each
+// of them is resolved with GetInstance.
+func (c *gitopsApiClient) ListInstances(ctx context.Context) ([]gitopsApiInstance, error) {
+	var instanceIds []string
+	err := c.execute(ctx, resty.MethodGet, "/instances", true, func(req *resty.Request) {
+		req.SetResult(&instanceIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]gitopsApiInstance, 0, len(instanceIds))
+	for _, instanceId := range instanceIds {
+		instance, err := c.GetInstance(ctx, instanceId)
+		if isNotFound(err) {
+			// Deleted since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (c *gitopsApiClient) PutInstance(ctx context.Context, instance_id string, instance_update instanceUpdate) (gitopsApiInstance, error) {
+	var instance gitopsApiInstance
+	err := c.execute(ctx, resty.MethodPut, "/instances/"+instance_id, true, func(req *resty.Request) {
+		req.SetBody(instance_update).SetResult(&instance)
+	})
+	return instance, err
+}
+
+// instanceChange is a change of an instance recorded by the Gitops API.
+type instanceChange struct {
+	Timestamp string `json:"timestamp"`
+	// Actor is the principal that ordered or changed the instance
+	Actor          string   `json:"actor"`
+	Changed_fields []string `json:"changed_fields"`
+	// Commit_sha is the git commit the GitOps pipeline made for the change
+	Commit_sha        string            `json:"commit_sha"`
+	Stage_transitions []stageTransition `json:"stage_transitions"`
+}
+
+// stageTransition is a stage change of an instance rolling out a change.
+type stageTransition struct {
+	Timestamp  string `json:"timestamp"`
+	From_stage string `json:"from_stage"`
+	To_stage   string `json:"to_stage"`
+}
+
+// GetInstanceHistory returns the changes of an instance, starting with its
+// order.
+func (c *gitopsApiClient) GetInstanceHistory(ctx context.Context, instance_id string) ([]instanceChange, error) {
+	var changes []instanceChange
+	err := c.execute(ctx, resty.MethodGet, "/instances/"+instance_id+"/history", true, func(req *resty.Request) {
+		req.SetResult(&changes)
+	})
+	return changes, err
+}
+
+func (c *gitopsApiClient) DeleteInstance(ctx context.Context, instance_id string) error {
+	return c.execute(ctx, resty.MethodDelete, "/instances/"+instance_id, true, func(req *resty.Request) {})
+}
+
+// gitopsApiService is a service of the Gitops API catalog.
+type gitopsApiService struct {
+	Service_id  uint64 `json:"service_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Versions are the versions instances of the service can be ordered
+	// with
+	Versions          []string `json:"versions"`
+	Min_replica_count uint64   `json:"min_replica_count"`
+	Max_replica_count uint64   `json:"max_replica_count"`
+	// Required_fields are the fields an order of the service has to set
+	Required_fields []string `json:"required_fields"`
+}
+
+// ListServices returns the services of the catalog.
+func (c *gitopsApiClient) ListServices(ctx context.Context) ([]gitopsApiService, error) {
+	var services []gitopsApiService
+	err := c.execute(ctx, resty.MethodGet, "/services", true, func(req *resty.Request) {
+		req.SetResult(&services)
+	})
+	return services, err
+}
+
+func (c *gitopsApiClient) GetService(ctx context.Context, service_id uint64) (gitopsApiService, error) {
+	var service gitopsApiService
+	err := c.execute(ctx, resty.MethodGet, "/services/"+strconv.FormatUint(service_id, 10), true, func(req *resty.Request) {
+		req.SetResult(&service)
+	})
+	return service, err
+}
+
+// gitopsApiManifest is a GitOps managed object of any service type of the
+// catalog, described by a free-form spec.
+type gitopsApiManifest struct {
+	Manifest_id  string          `json:"manifest_id"`
+	Order_time   string          `json:"order_time"`
+	Service_type string          `json:"service_type"`
+	Spec         json.RawMessage `json:"spec"`
+	// Stage is the stage of the manifest in the GitOps pipeline, e.g.
+	// "deploying" or "deployed"
+	Stage string `json:"stage"`
+	// Status is a human readable description of the rollout state
+	Status string `json:"status"`
+}
+
+// manifestOrder orders a new manifest.
+type manifestOrder struct {
+	Service_type string          `json:"service_type"`
+	Spec         json.RawMessage `json:"spec"`
+	// Environment lets the Gitops API reject orders meant for another
+	// environment.
+	Environment string `json:"environment,omitempty"`
+}
+
+// manifestUpdate replaces the spec of a manifest.
+type manifestUpdate struct {
+	Spec json.RawMessage `json:"spec"`
+}
+
+// PostManifestOrder orders a new manifest. Like instance orders, the order
+// is only retried if an idempotencyKey is given.
+func (c *gitopsApiClient) PostManifestOrder(ctx context.Context, order_request manifestOrder, idempotencyKey string) (gitopsApiManifest, error) {
+	var manifest gitopsApiManifest
+	order_request.Environment = c.environment
+	err := c.execute(ctx, resty.MethodPost, "/manifests", idempotencyKey != "", func(req *resty.Request) {
+		req.SetBody(order_request).SetResult(&manifest)
+		if idempotencyKey != "" {
+			req.SetHeader("Idempotency-Key", idempotencyKey)
+		}
+	})
+	return manifest, err
+}
+
+func (c *gitopsApiClient) GetManifest(ctx context.Context, manifest_id string) (gitopsApiManifest, error) {
+	var manifest gitopsApiManifest
+	err := c.execute(ctx, resty.MethodGet, "/manifests/"+manifest_id, true, func(req *resty.Request) {
+		req.SetResult(&manifest)
+	})
+	return manifest, err
+}
+
+// ListManifests returns every manifest visible to the authenticated
+// principal. Like the instances endpoint, the manifests endpoint only
+// lists manifest IDs, so each of them is resolved with GetManifest.
+func (c *gitopsApiClient) ListManifests(ctx context.Context) ([]gitopsApiManifest, error) {
+	var manifestIds []string
+	err := c.execute(ctx, resty.MethodGet, "/manifests", true, func(req *resty.Request) {
+		req.SetResult(&manifestIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := make([]gitopsApiManifest, 0, len(manifestIds))
+	for _, manifestId := range manifestIds {
+		manifest, err := c.GetManifest(ctx, manifestId)
+		if isNotFound(err) {
+			// Deleted since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+func (c *gitopsApiClient) PutManifest(ctx context.Context, manifest_id string, manifest_update manifestUpdate) (gitopsApiManifest, error) {
+	var manifest gitopsApiManifest
+	err := c.execute(ctx, resty.MethodPut, "/manifests/"+manifest_id, true, func(req *resty.Request) {
+		req.SetBody(manifest_update).SetResult(&manifest)
+	})
+	return manifest, err
+}
+
+func (c *gitopsApiClient) DeleteManifest(ctx context.Context, manifest_id string) error {
+	return c.execute(ctx, resty.MethodDelete, "/manifests/"+manifest_id, true, func(req *resty.Request) {})
+}
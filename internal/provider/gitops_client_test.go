@@ -0,0 +1,230 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chillout2k/gitopsclient"
+)
+
+// newTestApiClient returns a client for the API served by handler, with
+// a valid access token.
+func newTestApiClient(t *testing.T, handler http.Handler, retryPolicy gitopsRetryPolicy) *gitopsApiClient {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/certs", testJwks)
+	mux.Handle("/", handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := gitopsclient.NewGitopsClient(gitopsclient.GitopsClientConfig{
+		GitopsApiURI: server.URL,
+		CachePath:    t.TempDir(),
+		JwksURI:      server.URL + "/certs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.AccessToken = testAccessToken(t, time.Hour)
+	return newGitopsApiClient(client, retryPolicy)
+}
+
+// testRetryPolicy retries quickly to keep the tests fast.
+var testRetryPolicy = gitopsRetryPolicy{
+	MaxRetries: 3,
+	MinWait:    time.Millisecond,
+	MaxWait:    5 * time.Millisecond,
+}
+
+// failingHandler answers the first failures requests with status and
+// then returns instance 1. It counts all requests in calls.
+func failingHandler(calls *atomic.Int32, failures int32, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		writeJSON(w, gitopsclient.Instance{Instance_id: "1", Stage: "deployed"})
+	}
+}
+
+func TestGitopsApiClientRetriesTransientErrors(t *testing.T) {
+	for _, status := range []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	} {
+		var calls atomic.Int32
+		client := newTestApiClient(t, failingHandler(&calls, 2, status), testRetryPolicy)
+
+		instance, err := client.GetInstance(context.Background(), "1")
+		if err != nil {
+			t.Fatalf("status %d: unexpected error: %s", status, err)
+		}
+		if instance.Instance_id != "1" {
+			t.Errorf("status %d: got instance %q, want 1", status, instance.Instance_id)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("status %d: got %d calls, want 3", status, calls.Load())
+		}
+	}
+}
+
+func TestGitopsApiClientGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestApiClient(t, failingHandler(&calls, 100, http.StatusBadGateway), testRetryPolicy)
+
+	_, err := client.GetInstance(context.Background(), "1")
+	var apiErr *gitopsApiError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("got error %v, want 502 gitopsApiError", err)
+	}
+	if calls.Load() != int32(testRetryPolicy.MaxRetries)+1 {
+		t.Errorf("got %d calls, want %d", calls.Load(), testRetryPolicy.MaxRetries+1)
+	}
+}
+
+func TestGitopsApiClientDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestApiClient(t, failingHandler(&calls, 1, http.StatusBadRequest), testRetryPolicy)
+
+	_, err := client.GetInstance(context.Background(), "1")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("got %d calls, want 1", calls.Load())
+	}
+}
+
+func TestGitopsApiClientRetriesOrdersOnlyWithIdempotencyKey(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestApiClient(t, failingHandler(&calls, 1, http.StatusBadGateway), testRetryPolicy)
+
+	_, err := client.PostInstanceOrder(context.Background(), instanceOrder{}, "")
+	if err == nil {
+		t.Fatal("expected an error for an order without idempotency key")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("got %d calls without idempotency key, want 1", calls.Load())
+	}
+
+	calls.Store(0)
+	var keys []string
+	handler := failingHandler(&calls, 1, http.StatusBadGateway)
+	client = newTestApiClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		handler(w, r)
+	}), testRetryPolicy)
+
+	_, err = client.PostInstanceOrder(context.Background(), instanceOrder{}, "order-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(keys) != 2 || keys[0] != "order-1" || keys[1] != "order-1" {
+		t.Errorf("got idempotency keys %v, want the key on both attempts", keys)
+	}
+}
+
+func TestGitopsApiClientRetriesConnectionErrors(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestApiClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// Drop the connection without any response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		writeJSON(w, gitopsclient.Instance{Instance_id: "1"})
+	}), testRetryPolicy)
+
+	if _, err := client.GetInstance(context.Background(), "1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("got %d calls, want 2", calls.Load())
+	}
+}
+
+func TestGitopsApiClientHonoursRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	var retryAt time.Time
+	client := newTestApiClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			retryAt = time.Now().Add(time.Second)
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if time.Now().Before(retryAt) {
+			t.Errorf("retried %s before Retry-After", time.Until(retryAt))
+		}
+		writeJSON(w, gitopsclient.Instance{Instance_id: "1"})
+	}), gitopsRetryPolicy{MaxRetries: 1, MinWait: time.Millisecond, MaxWait: 2 * time.Second})
+
+	if _, err := client.GetInstance(context.Background(), "1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestGitopsApiClientListSkipsDeletedInstances(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc("GET /instances", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, []string{"1", "2"})
+	})
+	// Instance 1 is deleted after the list was served
+	handler.HandleFunc("GET /instances/1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSONStatus(w, http.StatusNotFound, map[string]string{"detail": "Instance not found"})
+	})
+	handler.HandleFunc("GET /instances/2", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, gitopsclient.Instance{Instance_id: "2", Stage: "deployed"})
+	})
+	client := newTestApiClient(t, handler, testRetryPolicy)
+
+	instances, err := client.ListInstances(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(instances) != 1 || instances[0].Instance_id != "2" {
+		t.Errorf("got instances %v, want only instance 2", instances)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		"empty":    {value: "", ok: false},
+		"seconds":  {value: "120", want: 2 * time.Minute, ok: true},
+		"negative": {value: "-1", ok: false},
+		"date":     {value: "Mon, 01 Jul 2024 12:00:30 GMT", want: 30 * time.Second, ok: true},
+		"past":     {value: "Mon, 01 Jul 2024 11:00:00 GMT", want: 0, ok: true},
+		"invalid":  {value: "soon", ok: false},
+	}
+	for name, test := range tests {
+		got, ok := parseRetryAfter(test.value, now)
+		if ok != test.ok || got != test.want {
+			t.Errorf("%s: got (%s, %t), want (%s, %t)", name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestRetryPolicyWaitIsBounded(t *testing.T) {
+	policy := gitopsRetryPolicy{MaxRetries: 10, MinWait: time.Second, MaxWait: 8 * time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		upper := min(time.Second<<attempt, policy.MaxWait)
+		wait := policy.wait(attempt, nil)
+		if wait < upper/2 || wait > upper {
+			t.Errorf("attempt %d: got wait %s, want between %s and %s", attempt, wait, upper/2, upper)
+		}
+	}
+}
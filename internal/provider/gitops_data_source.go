@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/chillout2k/gitopsclient"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 // Ensure the implementation satisfies the expected interfaces.
@@ -22,25 +22,32 @@ func NewGitopsDataSource() datasource.DataSource {
 
 // gitopsDataSource is the data source implementation.
 type gitopsDataSource struct {
-	//client *hashicups.Client
-	client *gitopsclient.GitopsClient
+	client *gitopsApiClient
 }
 
 // gitopsDataSourceModel maps the data source schema data.
-/*type gitopsDataSourceModel struct {
-	Plans []gitopsPlanModel `tfsdk:"plans"`
+type gitopsDataSourceModel struct {
+	Orderer_id   types.String      `tfsdk:"orderer_id"`
+	Bits_account types.Int64       `tfsdk:"bits_account"`
+	Service_id   types.Int64       `tfsdk:"service_id"`
+	Stage        types.String      `tfsdk:"stage"`
+	Plans        []gitopsPlanModel `tfsdk:"plans"`
 }
 
 // gitopsPlanModel maps gitops instance schema data.
 type gitopsPlanModel struct {
-	Instance_name string `tfsdk:"instance_name"`
-	Orderer_id    string `tfsdk:"orderer_id"`
-	Bits_account  uint64 `tfsdk:"bits_account"`
-	Service_id    uint64 `tfsdk:"service_id"`
-	Replica_count uint64 `tfsdk:"replica_count"`
-	Version       string `tfsdk:"version"`
-	Some_value    string `tfsdk:"some_value"`
-}*/
+	Instance_id   types.String `tfsdk:"instance_id"`
+	Order_time    types.String `tfsdk:"order_time"`
+	Stage         types.String `tfsdk:"stage"`
+	Instance_name types.String `tfsdk:"instance_name"`
+	Orderer_id    types.String `tfsdk:"orderer_id"`
+	Bits_account  types.Int64  `tfsdk:"bits_account"`
+	Service_id    types.Int64  `tfsdk:"service_id"`
+	Replica_count types.Int64  `tfsdk:"replica_count"`
+	Version       types.String `tfsdk:"version"`
+	Some_value    types.String `tfsdk:"some_value"`
+	Labels        types.Map    `tfsdk:"labels"`
+}
 
 // Metadata returns the data source type name.
 func (d *gitopsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -50,18 +57,47 @@ func (d *gitopsDataSource) Metadata(_ context.Context, req datasource.MetadataRe
 // Schema defines the schema for the data source.
 func (d *gitopsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Description: "Manages a Gitops instance order",
+		Description: "Lists the Gitops instances visible to the authenticated principal",
 		Attributes: map[string]schema.Attribute{
+			"orderer_id": schema.StringAttribute{
+				Description: "Only list instances ordered by this orderer",
+				Optional:    true,
+			},
+			"bits_account": schema.Int64Attribute{
+				Description: "Only list instances of this account",
+				Optional:    true,
+			},
+			"service_id": schema.Int64Attribute{
+				Description: "Only list instances of this service",
+				Optional:    true,
+			},
+			"stage": schema.StringAttribute{
+				Description: "Only list instances in this stage",
+				Optional:    true,
+			},
 			"plans": schema.ListNestedAttribute{
-				Computed: true,
+				Description: "Gitops instances matching the filters",
+				Computed:    true,
 				NestedObject: schema.NestedAttributeObject{
 					Attributes: map[string]schema.Attribute{
+						"instance_id": schema.StringAttribute{
+							Description: "ID of the Gitops resource instance",
+							Computed:    true,
+						},
+						"order_time": schema.StringAttribute{
+							Description: "Order time of the Gitops resource instance",
+							Computed:    true,
+						},
+						"stage": schema.StringAttribute{
+							Description: "Stage",
+							Computed:    true,
+						},
 						"instance_name": schema.StringAttribute{
 							Description: "Name of the Gitops resource instance",
 							Computed:    true,
 						},
 						"orderer_id": schema.StringAttribute{
-							Description: "Name of the Gitops resource orderer",
+							Description: "ID of the Gitops resource orderer",
 							Computed:    true,
 						},
 						"bits_account": schema.Int64Attribute{
@@ -84,6 +120,11 @@ func (d *gitopsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest,
 							Description: "Some custom value of the Gitops resource instance",
 							Computed:    true,
 						},
+						"labels": schema.MapAttribute{
+							Description: "Labels of the Gitops resource instance, including the default labels it was ordered with",
+							ElementType: types.StringType,
+							Computed:    true,
+						},
 					},
 				},
 			},
@@ -93,38 +134,62 @@ func (d *gitopsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest,
 
 // Read refreshes the Terraform state with the latest data.
 func (d *gitopsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
-	/*var state gitopsDataSourceModel
+	var state gitopsDataSourceModel
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	plans, err := d.client.getPlans()
+	instances, err := d.client.ListInstances(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		addApiErrorDiagnostics(&resp.Diagnostics,
 			"Unable to Read gitops Plans",
-			err.Error(),
+			"Could not list gitops instances",
+			err,
 		)
 		return
 	}
 
-	// Map response body to model
-	for _, plan := range plans {
+	// Map response body to model, skipping instances not matching the filters
+	state.Plans = []gitopsPlanModel{}
+	for _, instance := range instances {
+		if !state.Orderer_id.IsNull() && state.Orderer_id.ValueString() != instance.Orderer_id {
+			continue
+		}
+		if !state.Bits_account.IsNull() && uint64(state.Bits_account.ValueInt64()) != instance.Bits_account {
+			continue
+		}
+		if !state.Service_id.IsNull() && uint64(state.Service_id.ValueInt64()) != instance.Service_id {
+			continue
+		}
+		if !state.Stage.IsNull() && state.Stage.ValueString() != instance.Stage {
+			continue
+		}
+
 		planState := gitopsPlanModel{
-			Instance_name: plan.Instance_id,
-			Orderer_id:    plan.Order_time,
-			Bits_account:  uint64(plan.Bits_account),
-			Service_id:    uint64(plan.Service_id),
-			Replica_count: uint64(plan.Replica_count),
-			Version:       plan.Version,
-			Some_value:    plan.Some_value,
+			Instance_id:   types.StringValue(instance.Instance_id),
+			Order_time:    types.StringValue(instance.Order_time),
+			Stage:         types.StringValue(instance.Stage),
+			Instance_name: types.StringValue(instance.Instance_name),
+			Orderer_id:    types.StringValue(instance.Orderer_id),
+			Bits_account:  types.Int64Value(int64(instance.Bits_account)),
+			Service_id:    types.Int64Value(int64(instance.Service_id)),
+			Replica_count: types.Int64Value(int64(instance.Replica_count)),
+			Version:       types.StringValue(instance.Version),
+			Some_value:    types.StringValue(instance.Some_value),
+			Labels:        labelsValue(instance.Metadata.Labels),
 		}
 
 		state.Plans = append(state.Plans, planState)
 	}
 
 	// Set state
-	diags := resp.State.Set(ctx, &state)
+	diags = resp.State.Set(ctx, &state)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
-	}*/
+	}
 }
 
 // Configure adds the provider configured client to the data source.
@@ -135,11 +200,11 @@ func (d *gitopsDataSource) Configure(_ context.Context, req datasource.Configure
 		return
 	}
 
-	client, ok := req.ProviderData.(*gitopsclient.GitopsClient)
+	client, ok := req.ProviderData.(*gitopsApiClient)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *hashicups.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *gitopsApiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccGitopsPlansDataSource(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir()) + `
+resource "gitops_instance" "first" {
+  instance_name = "first"
+  orderer_id    = "jane.doe@example.com"
+  bits_account  = 4711
+  service_id    = 42
+  replica_count = 1
+  version       = "3.2.1"
+  some_value    = "some value"
+}
+
+resource "gitops_instance" "second" {
+  instance_name = "second"
+  orderer_id    = "john.doe@example.com"
+  bits_account  = 4712
+  service_id    = 42
+  replica_count = 2
+  version       = "3.2.1"
+  some_value    = "some value"
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config + `
+data "gitops_plans" "all" {
+  depends_on = [gitops_instance.first, gitops_instance.second]
+}
+
+data "gitops_plans" "account" {
+  bits_account = gitops_instance.second.bits_account
+}
+
+data "gitops_plans" "orderer" {
+  orderer_id = gitops_instance.first.orderer_id
+}
+
+data "gitops_plans" "none" {
+  service_id = 1
+  depends_on = [gitops_instance.first, gitops_instance.second]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.gitops_plans.all", "plans.#", "2"),
+					resource.TestCheckResourceAttr("data.gitops_plans.account", "plans.#", "1"),
+					resource.TestCheckResourceAttrPair("data.gitops_plans.account", "plans.0.instance_id", "gitops_instance.second", "instance_id"),
+					resource.TestCheckResourceAttr("data.gitops_plans.account", "plans.0.instance_name", "second"),
+					resource.TestCheckResourceAttr("data.gitops_plans.account", "plans.0.replica_count", "2"),
+					resource.TestCheckResourceAttr("data.gitops_plans.account", "plans.0.stage", "deployed"),
+					resource.TestCheckResourceAttr("data.gitops_plans.orderer", "plans.#", "1"),
+					resource.TestCheckResourceAttrPair("data.gitops_plans.orderer", "plans.0.instance_id", "gitops_instance.first", "instance_id"),
+					resource.TestCheckResourceAttr("data.gitops_plans.none", "plans.#", "0"),
+				),
+			},
+		},
+	})
+}
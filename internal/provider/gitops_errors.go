@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// gitopsApiError is returned for Gitops API requests answered with a
+// non-2xx status code.
+type gitopsApiError struct {
+	StatusCode int
+	Status     string
+	Code       string
+	Message    string
+	Details    []gitopsApiErrorDetail
+	RequestId  string
+}
+
+// gitopsApiErrorDetail is a validation error reported for a single field.
+type gitopsApiErrorDetail struct {
+	Field   string
+	Message string
+}
+
+// gitopsApiErrorBody covers the plain error body of the Gitops API
+// ({"code": ..., "message": ..., "errors": [...]}), validation errors
+// of its request parsing ({"detail": [{"loc": [...], "msg": ...}]}) and
+// OAuth2 token endpoint errors ({"error": ..., "error_description": ...}).
+type gitopsApiErrorBody struct {
+	Code             string          `json:"code"`
+	Message          string          `json:"message"`
+	Error            string          `json:"error"`
+	ErrorDescription string          `json:"error_description"`
+	Detail           json.RawMessage `json:"detail"`
+	Errors           []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// newGitopsApiError parses the error body of resp.
+func newGitopsApiError(resp *resty.Response) *gitopsApiError {
+	apiErr := &gitopsApiError{
+		StatusCode: resp.StatusCode(),
+		Status:     resp.Status(),
+		RequestId:  resp.Header().Get("X-Request-Id"),
+	}
+
+	var body gitopsApiErrorBody
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		apiErr.Message = strings.TrimSpace(string(resp.Body()))
+		return apiErr
+	}
+	apiErr.Code = body.Code
+	apiErr.Message = body.Message
+	if apiErr.Message == "" && body.ErrorDescription != "" {
+		apiErr.Code = cmp.Or(apiErr.Code, body.Error)
+		apiErr.Message = body.ErrorDescription
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = body.Error
+	}
+	for _, fieldErr := range body.Errors {
+		apiErr.Details = append(apiErr.Details, gitopsApiErrorDetail{
+			Field:   fieldErr.Field,
+			Message: fieldErr.Message,
+		})
+	}
+
+	var detailMessage string
+	var detailErrors []struct {
+		Loc  []any  `json:"loc"`
+		Msg  string `json:"msg"`
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(body.Detail, &detailMessage) == nil && apiErr.Message == "" {
+		apiErr.Message = detailMessage
+	} else if json.Unmarshal(body.Detail, &detailErrors) == nil {
+		for _, detailErr := range detailErrors {
+			// loc is the location of the invalid value, e.g. ["body", "replica_count"]
+			var field string
+			if len(detailErr.Loc) == 2 && detailErr.Loc[0] == "body" {
+				field, _ = detailErr.Loc[1].(string)
+			}
+			apiErr.Details = append(apiErr.Details, gitopsApiErrorDetail{
+				Field:   field,
+				Message: detailErr.Msg,
+			})
+			if apiErr.Code == "" {
+				apiErr.Code = detailErr.Type
+			}
+		}
+	}
+
+	if apiErr.Message == "" && len(apiErr.Details) > 0 {
+		apiErr.Message = "request validation failed"
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(apiErr.StatusCode)
+	}
+	return apiErr
+}
+
+func (e *gitopsApiError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("GitopsClient error: " + e.Status)
+	if e.Code != "" {
+		sb.WriteString(" (" + e.Code + ")")
+	}
+	sb.WriteString(": " + e.Message)
+	for _, detail := range e.Details {
+		if detail.Field != "" {
+			sb.WriteString("; " + detail.Field + ": " + detail.Message)
+		} else {
+			sb.WriteString("; " + detail.Message)
+		}
+	}
+	if e.RequestId != "" {
+		sb.WriteString(" (request id: " + e.RequestId + ")")
+	}
+	return sb.String()
+}
+
+// gitopsRequestError is returned for Gitops API requests that failed
+// without a response after they were sent, so the Gitops API may have
+// processed them.
+type gitopsRequestError struct {
+	Err error
+}
+
+func (e *gitopsRequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *gitopsRequestError) Unwrap() error {
+	return e.Err
+}
+
+// isNotFound reports whether err was caused by a missing API object.
+func isNotFound(err error) bool {
+	var apiErr *gitopsApiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// addApiErrorDiagnostics explains err in diags. Errors of the Gitops API
+// get a summary and hint matching their status code, and validation
+// errors are attached to the attribute they were reported for if it is
+// one of attributes. Other errors are reported under summary.
+func addApiErrorDiagnostics(diags *diag.Diagnostics, summary string, detail string, err error, attributes ...string) {
+	var apiErr *gitopsApiError
+	if !errors.As(err, &apiErr) {
+		diags.AddError(summary, detail+", unexpected error: "+err.Error())
+		return
+	}
+
+	var hint string
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		summary = "Gitops API Authentication Failed"
+		hint = "Check the provider credentials. The cached access token in cache_path may have expired or been revoked."
+	case apiErr.StatusCode == http.StatusForbidden:
+		summary = "Gitops API Permission Denied"
+		hint = "The authenticated principal is not allowed to perform this operation. Check its roles and the requested scopes."
+	case apiErr.StatusCode == http.StatusNotFound:
+		summary = "Gitops API Object Not Found"
+		hint = "The object does not exist or is not visible to the authenticated principal."
+	case apiErr.StatusCode == http.StatusConflict:
+		summary = "Gitops API Conflict"
+		hint = "The object already exists or was changed concurrently. Refresh the state and retry."
+	case apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity:
+		summary = "Gitops API Rejected the Request"
+		hint = "Fix the reported values in the configuration and retry."
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		summary = "Gitops API Rate Limit Exceeded"
+		hint = "Retry later or lower the number of concurrent operations with -parallelism."
+	case apiErr.StatusCode >= 500:
+		summary = "Gitops API Server Error"
+		hint = "The Gitops API failed to handle the request. Retry later and contact its operators if the problem persists."
+	}
+
+	// Every diagnostic carries the status, code, request ID and hint, so
+	// users have something to quote to the operators of the Gitops API
+	status := fmt.Sprintf(" (HTTP %d", apiErr.StatusCode)
+	if apiErr.Code != "" {
+		status += ", code " + apiErr.Code
+	}
+	if apiErr.RequestId != "" {
+		status += ", request id " + apiErr.RequestId
+	}
+	status += ")."
+	if hint != "" {
+		status += "\n\n" + hint
+	}
+	message := detail + ": " + apiErr.Message + status
+
+	var unmatched []string
+	for _, fieldErr := range apiErr.Details {
+		if slices.Contains(attributes, fieldErr.Field) {
+			diags.AddAttributeError(path.Root(fieldErr.Field), summary, detail+": "+fieldErr.Message+status)
+		} else if fieldErr.Field != "" {
+			unmatched = append(unmatched, fieldErr.Field+": "+fieldErr.Message)
+		} else {
+			unmatched = append(unmatched, fieldErr.Message)
+		}
+	}
+	if len(unmatched) > 0 {
+		message += "\n\nValidation errors:\n  - " + strings.Join(unmatched, "\n  - ")
+	}
+	if len(unmatched) > 0 || len(apiErr.Details) == 0 {
+		diags.AddError(summary, message)
+	}
+}
@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+func TestAddApiErrorDiagnosticsAttributeErrors(t *testing.T) {
+	err := &gitopsApiError{
+		StatusCode: http.StatusUnprocessableEntity,
+		Status:     "422 Unprocessable Entity",
+		Code:       "value_error",
+		Message:    "request validation failed",
+		Details:    []gitopsApiErrorDetail{{Field: "replica_count", Message: "replica count exceeds the quota"}},
+		RequestId:  "req-123",
+	}
+
+	var diags diag.Diagnostics
+	addApiErrorDiagnostics(&diags, "Error creating instance gitopsInstance", "Could not create gitopsInstance", err, "replica_count")
+
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %v", len(diags), diags)
+	}
+	withPath, ok := diags[0].(diag.DiagnosticWithPath)
+	if !ok || !withPath.Path().Equal(path.Root("replica_count")) {
+		t.Errorf("got diagnostic %v, want it attached to replica_count", diags[0])
+	}
+	for _, want := range []string{"replica count exceeds the quota", "HTTP 422", "code value_error", "request id req-123", "Fix the reported values"} {
+		if !strings.Contains(diags[0].Detail(), want) {
+			t.Errorf("got detail %q, want it to contain %q", diags[0].Detail(), want)
+		}
+	}
+}
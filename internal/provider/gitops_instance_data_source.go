@@ -0,0 +1,249 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource                     = &gitopsInstanceDataSource{}
+	_ datasource.DataSourceWithConfigure        = &gitopsInstanceDataSource{}
+	_ datasource.DataSourceWithConfigValidators = &gitopsInstanceDataSource{}
+)
+
+// NewGitopsInstanceDataSource is a helper function to simplify the provider implementation.
+func NewGitopsInstanceDataSource() datasource.DataSource {
+	return &gitopsInstanceDataSource{}
+}
+
+// gitopsInstanceDataSource is the data source implementation.
+type gitopsInstanceDataSource struct {
+	client *gitopsApiClient
+}
+
+// gitopsInstanceDataSourceModel maps the data source schema data.
+type gitopsInstanceDataSourceModel struct {
+	Instance_id     types.String `tfsdk:"instance_id"`
+	Order_time      types.String `tfsdk:"order_time"`
+	Stage           types.String `tfsdk:"stage"`
+	Instance_name   types.String `tfsdk:"instance_name"`
+	Orderer_id      types.String `tfsdk:"orderer_id"`
+	Bits_account    types.Int64  `tfsdk:"bits_account"`
+	Service_id      types.Int64  `tfsdk:"service_id"`
+	Replica_count   types.Int64  `tfsdk:"replica_count"`
+	Version         types.String `tfsdk:"version"`
+	ResolvedVersion types.String `tfsdk:"resolved_version"`
+	Some_value      types.String `tfsdk:"some_value"`
+	Labels          types.Map    `tfsdk:"labels"`
+	LastUpdated     types.String `tfsdk:"last_updated"`
+}
+
+// Metadata returns the data source type name.
+func (d *gitopsInstanceDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_instance"
+}
+
+// Schema defines the schema for the data source.
+func (d *gitopsInstanceDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Looks up a Gitops instance by instance_id or by instance_name and bits_account",
+		Attributes: map[string]schema.Attribute{
+			"instance_id": schema.StringAttribute{
+				Description: "ID of the Gitops resource instance",
+				Optional:    true,
+				Computed:    true,
+			},
+			"order_time": schema.StringAttribute{
+				Description: "Order time of the Gitops resource instance",
+				Computed:    true,
+			},
+			"stage": schema.StringAttribute{
+				Description: "Stage",
+				Computed:    true,
+			},
+			"last_updated": schema.StringAttribute{
+				Description: "Timestamp of the lookup",
+				Computed:    true,
+			},
+			"instance_name": schema.StringAttribute{
+				Description: "Name of the Gitops resource instance. Requires bits_account to be set.",
+				Optional:    true,
+				Computed:    true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("bits_account")),
+				},
+			},
+			"orderer_id": schema.StringAttribute{
+				Description: "ID of the Gitops resource orderer",
+				Computed:    true,
+			},
+			"bits_account": schema.Int64Attribute{
+				Description: "Account-ID of the Gitops resource instance",
+				Optional:    true,
+				Computed:    true,
+			},
+			"service_id": schema.Int64Attribute{
+				Description: "Service-ID of the Gitops resource instance",
+				Computed:    true,
+			},
+			"replica_count": schema.Int64Attribute{
+				Description: "Replica count of the Gitops resource instance",
+				Computed:    true,
+			},
+			"version": schema.StringAttribute{
+				Description: "Version of the Gitops resource instance",
+				Computed:    true,
+			},
+			"resolved_version": schema.StringAttribute{
+				Description: "Concrete version of the Gitops resource instance satisfying version: the version reported by the Gitops API, " +
+					"or the highest version of the service in the catalog satisfying a wildcard version.",
+				Computed: true,
+			},
+			"some_value": schema.StringAttribute{
+				Description: "Some custom value of the Gitops resource instance",
+				Computed:    true,
+			},
+			"labels": schema.MapAttribute{
+				Description: "Labels of the Gitops resource instance, including the default labels it was ordered with",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// ConfigValidators ensures the instance is looked up either by ID or by name.
+func (d *gitopsInstanceDataSource) ConfigValidators(_ context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(
+			path.MatchRoot("instance_id"),
+			path.MatchRoot("instance_name"),
+		),
+	}
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *gitopsInstanceDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state gitopsInstanceDataSourceModel
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var gitopsInstance gitopsApiInstance
+	if !state.Instance_id.IsNull() {
+		instance, err := d.client.GetInstance(ctx, state.Instance_id.ValueString())
+		if isNotFound(err) {
+			resp.Diagnostics.AddError(
+				"No gitopsInstance Found",
+				fmt.Sprintf("No instance with ID %q exists.", state.Instance_id.ValueString()),
+			)
+			return
+		}
+		if err != nil {
+			addApiErrorDiagnostics(&resp.Diagnostics,
+				"Unable to Read gitopsInstance",
+				"Could not read gitopsInstance ID "+state.Instance_id.ValueString(),
+				err,
+			)
+			return
+		}
+		gitopsInstance = instance
+	} else {
+		instances, err := d.client.ListInstances(ctx)
+		if err != nil {
+			addApiErrorDiagnostics(&resp.Diagnostics,
+				"Unable to Read gitopsInstance",
+				"Could not list gitops instances",
+				err,
+			)
+			return
+		}
+
+		var matches []gitopsApiInstance
+		for _, instance := range instances {
+			if instance.Instance_name == state.Instance_name.ValueString() &&
+				instance.Bits_account == uint64(state.Bits_account.ValueInt64()) {
+				matches = append(matches, instance)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			resp.Diagnostics.AddError(
+				"No gitopsInstance Found",
+				fmt.Sprintf("No instance named %q exists in bits_account %d.",
+					state.Instance_name.ValueString(), state.Bits_account.ValueInt64()),
+			)
+			return
+		case 1:
+			gitopsInstance = matches[0]
+		default:
+			instanceIds := make([]string, 0, len(matches))
+			for _, match := range matches {
+				instanceIds = append(instanceIds, match.Instance_id)
+			}
+			resp.Diagnostics.AddError(
+				"Multiple gitopsInstances Found",
+				fmt.Sprintf("The instance name %q is not unique in bits_account %d, matching instance IDs: %s. "+
+					"Look the instance up by instance_id instead.",
+					state.Instance_name.ValueString(), state.Bits_account.ValueInt64(), strings.Join(instanceIds, ", ")),
+			)
+			return
+		}
+	}
+
+	state.Instance_id = types.StringValue(gitopsInstance.Instance_id)
+	state.Order_time = types.StringValue(gitopsInstance.Order_time)
+	state.Stage = types.StringValue(gitopsInstance.Stage)
+	state.Instance_name = types.StringValue(gitopsInstance.Instance_name)
+	state.Orderer_id = types.StringValue(gitopsInstance.Orderer_id)
+	state.Bits_account = types.Int64Value(int64(gitopsInstance.Bits_account))
+	state.Service_id = types.Int64Value(int64(gitopsInstance.Service_id))
+	state.Replica_count = types.Int64Value(int64(gitopsInstance.Replica_count))
+	state.Version = types.StringValue(gitopsInstance.Version)
+	state.ResolvedVersion = d.client.resolvedVersion(ctx, gitopsInstance, types.StringNull())
+	state.Some_value = types.StringValue(gitopsInstance.Some_value)
+	state.Labels = labelsValue(gitopsInstance.Metadata.Labels)
+	state.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	// Set state
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *gitopsInstanceDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Add a nil check when handling ProviderData because Terraform
+	// sets that data after it calls the ConfigureProvider RPC.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*gitopsApiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *gitopsApiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccGitopsInstanceDataSource(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir()) + testAccInstanceConfig("test-instance", 2)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Look up by instance_id
+			{
+				Config: config + `
+data "gitops_instance" "test" {
+  instance_id = gitops_instance.test.instance_id
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("data.gitops_instance.test", "instance_id", "gitops_instance.test", "instance_id"),
+					resource.TestCheckResourceAttrPair("data.gitops_instance.test", "order_time", "gitops_instance.test", "order_time"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "stage", "deployed"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "instance_name", "test-instance"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "orderer_id", "jane.doe@example.com"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "bits_account", "4711"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "service_id", "42"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "replica_count", "2"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "version", "3.2.1"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "resolved_version", "3.2.1"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "some_value", "some value"),
+					resource.TestCheckResourceAttrSet("data.gitops_instance.test", "last_updated"),
+				),
+			},
+			// Look up by instance_name and bits_account
+			{
+				Config: config + `
+data "gitops_instance" "test" {
+  instance_name = gitops_instance.test.instance_name
+  bits_account  = gitops_instance.test.bits_account
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("data.gitops_instance.test", "instance_id", "gitops_instance.test", "instance_id"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "replica_count", "2"),
+				),
+			},
+			{
+				Config: config + `
+data "gitops_instance" "test" {
+  instance_id = "does-not-exist"
+}
+`,
+				ExpectError: regexp.MustCompile(`No gitopsInstance Found`),
+			},
+			{
+				Config: config + `
+data "gitops_instance" "test" {
+  instance_name = "does-not-exist"
+  bits_account  = gitops_instance.test.bits_account
+}
+`,
+				ExpectError: regexp.MustCompile(`No gitopsInstance Found`),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceDataSourceResolvesWildcardVersion(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir()) + strings.Replace(testAccInstanceConfig("test-instance", 1), `"3.2.1"`, `"3.2.*"`, 1)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config + `
+data "gitops_instance" "test" {
+  instance_id = gitops_instance.test.instance_id
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "version", "3.2.*"),
+					resource.TestCheckResourceAttr("data.gitops_instance.test", "resolved_version", "3.2.1"),
+					resource.TestCheckResourceAttrPair("data.gitops_instance.test", "resolved_version", "gitops_instance.test", "resolved_version"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceDataSourceRequiresLookupAttributes(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, t.TempDir()) + `
+data "gitops_instance" "test" {}
+`,
+				ExpectError: regexp.MustCompile(`Missing Attribute Configuration`),
+			},
+		},
+	})
+}
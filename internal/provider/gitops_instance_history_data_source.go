@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &gitopsInstanceHistoryDataSource{}
+	_ datasource.DataSourceWithConfigure = &gitopsInstanceHistoryDataSource{}
+)
+
+// NewGitopsInstanceHistoryDataSource is a helper function to simplify the provider implementation.
+func NewGitopsInstanceHistoryDataSource() datasource.DataSource {
+	return &gitopsInstanceHistoryDataSource{}
+}
+
+// gitopsInstanceHistoryDataSource is the data source implementation.
+type gitopsInstanceHistoryDataSource struct {
+	client *gitopsApiClient
+}
+
+// gitopsInstanceHistoryDataSourceModel maps the data source schema data.
+type gitopsInstanceHistoryDataSourceModel struct {
+	Instance_id types.String                `tfsdk:"instance_id"`
+	Changes     []gitopsInstanceChangeModel `tfsdk:"changes"`
+}
+
+// gitopsInstanceChangeModel maps instance change schema data.
+type gitopsInstanceChangeModel struct {
+	Timestamp         types.String                 `tfsdk:"timestamp"`
+	Actor             types.String                 `tfsdk:"actor"`
+	Changed_fields    types.List                   `tfsdk:"changed_fields"`
+	Commit_sha        types.String                 `tfsdk:"commit_sha"`
+	Stage_transitions []gitopsStageTransitionModel `tfsdk:"stage_transitions"`
+}
+
+// gitopsStageTransitionModel maps stage transition schema data.
+type gitopsStageTransitionModel struct {
+	Timestamp  types.String `tfsdk:"timestamp"`
+	From_stage types.String `tfsdk:"from_stage"`
+	To_stage   types.String `tfsdk:"to_stage"`
+}
+
+// newGitopsInstanceChangeModel maps an instance change to its schema data.
+func newGitopsInstanceChangeModel(change instanceChange) gitopsInstanceChangeModel {
+	model := gitopsInstanceChangeModel{
+		Timestamp:         types.StringValue(change.Timestamp),
+		Actor:             types.StringValue(change.Actor),
+		Changed_fields:    stringListValue(change.Changed_fields),
+		Commit_sha:        types.StringValue(change.Commit_sha),
+		Stage_transitions: make([]gitopsStageTransitionModel, 0, len(change.Stage_transitions)),
+	}
+	for _, transition := range change.Stage_transitions {
+		model.Stage_transitions = append(model.Stage_transitions, gitopsStageTransitionModel{
+			Timestamp:  types.StringValue(transition.Timestamp),
+			From_stage: types.StringValue(transition.From_stage),
+			To_stage:   types.StringValue(transition.To_stage),
+		})
+	}
+	return model
+}
+
+// Metadata returns the data source type name.
+func (d *gitopsInstanceHistoryDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_instance_history"
+}
+
+// Schema defines the schema for the data source.
+func (d *gitopsInstanceHistoryDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the changes of a Gitops instance since it was ordered, for audits and compliance reports",
+		Attributes: map[string]schema.Attribute{
+			"instance_id": schema.StringAttribute{
+				Description: "ID of the Gitops resource instance",
+				Required:    true,
+			},
+			"changes": schema.ListNestedAttribute{
+				Description: "Changes of the instance, oldest first. The first change is the order of the instance.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"timestamp": schema.StringAttribute{
+							Description: "Time of the change",
+							Computed:    true,
+						},
+						"actor": schema.StringAttribute{
+							Description: "Principal that made the change",
+							Computed:    true,
+						},
+						"changed_fields": schema.ListAttribute{
+							Description: "Fields of the instance set by the change",
+							ElementType: types.StringType,
+							Computed:    true,
+						},
+						"commit_sha": schema.StringAttribute{
+							Description: "SHA of the git commit made for the change",
+							Computed:    true,
+						},
+						"stage_transitions": schema.ListNestedAttribute{
+							Description: "Stages the instance went through rolling out the change, oldest first",
+							Computed:    true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"timestamp": schema.StringAttribute{
+										Description: "Time of the transition",
+										Computed:    true,
+									},
+									"from_stage": schema.StringAttribute{
+										Description: "Stage before the transition, empty for the order",
+										Computed:    true,
+									},
+									"to_stage": schema.StringAttribute{
+										Description: "Stage after the transition",
+										Computed:    true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *gitopsInstanceHistoryDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state gitopsInstanceHistoryDataSourceModel
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes, err := d.client.GetInstanceHistory(ctx, state.Instance_id.ValueString())
+	if isNotFound(err) {
+		resp.Diagnostics.AddError(
+			"No gitopsInstance Found",
+			fmt.Sprintf("No instance with ID %q exists.", state.Instance_id.ValueString()),
+		)
+		return
+	}
+	if err != nil {
+		addApiErrorDiagnostics(&resp.Diagnostics,
+			"Unable to Read gitopsInstance History",
+			"Could not read the history of gitopsInstance ID "+state.Instance_id.ValueString(),
+			err,
+		)
+		return
+	}
+
+	// Reports rely on the order of the changes, do not depend on the API
+	// returning them sorted
+	sortInstanceChanges(changes)
+
+	state.Changes = make([]gitopsInstanceChangeModel, 0, len(changes))
+	for _, change := range changes {
+		state.Changes = append(state.Changes, newGitopsInstanceChangeModel(change))
+	}
+
+	// Set state
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// sortInstanceChanges sorts changes by their timestamps, oldest first.
+// Changes are kept in the order of the API if a timestamp cannot be parsed.
+func sortInstanceChanges(changes []instanceChange) {
+	timestamps := make(map[string]time.Time, len(changes))
+	for _, change := range changes {
+		timestamp, err := time.Parse(time.RFC3339Nano, change.Timestamp)
+		if err != nil {
+			return
+		}
+		timestamps[change.Timestamp] = timestamp
+	}
+	slices.SortStableFunc(changes, func(a, b instanceChange) int {
+		return timestamps[a.Timestamp].Compare(timestamps[b.Timestamp])
+	})
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *gitopsInstanceHistoryDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Add a nil check when handling ProviderData because Terraform
+	// sets that data after it calls the ConfigureProvider RPC.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*gitopsApiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *gitopsApiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccGitopsInstanceHistoryDataSource(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	providerConfig := testAccProviderConfig(api, t.TempDir())
+	historyConfig := `
+data "gitops_instance_history" "test" {
+  instance_id = gitops_instance.test.instance_id
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// The order is the first change
+			{
+				Config: providerConfig + testAccInstanceConfig("test-instance", 2) + historyConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.#", "1"),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.0.actor", fakeUsername),
+					resource.TestCheckTypeSetElemAttr("data.gitops_instance_history.test", "changes.0.changed_fields.*", "instance_name"),
+					resource.TestCheckTypeSetElemAttr("data.gitops_instance_history.test", "changes.0.changed_fields.*", "replica_count"),
+					resource.TestMatchResourceAttr("data.gitops_instance_history.test", "changes.0.commit_sha", regexp.MustCompile(`^[0-9a-f]{40}$`)),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.0.stage_transitions.#", "2"),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.0.stage_transitions.0.from_stage", ""),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.0.stage_transitions.0.to_stage", "deploying"),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.0.stage_transitions.1.from_stage", "deploying"),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.0.stage_transitions.1.to_stage", "deployed"),
+				),
+			},
+			{
+				Config: providerConfig + testAccInstanceConfig("test-instance", 3) + historyConfig,
+			},
+			// The history is read before the update is applied, it shows up
+			// on the next refresh
+			{
+				Config: providerConfig + testAccInstanceConfig("test-instance", 3) + historyConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.#", "2"),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.1.actor", fakeUsername),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.1.changed_fields.#", "1"),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.1.changed_fields.0", "replica_count"),
+					resource.TestMatchResourceAttr("data.gitops_instance_history.test", "changes.1.commit_sha", regexp.MustCompile(`^[0-9a-f]{40}$`)),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.1.stage_transitions.#", "2"),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.1.stage_transitions.0.from_stage", "deployed"),
+					resource.TestCheckResourceAttr("data.gitops_instance_history.test", "changes.1.stage_transitions.1.to_stage", "deployed"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "gitops_instance_history" "test" {
+  instance_id = "does-not-exist"
+}
+`,
+				ExpectError: regexp.MustCompile(`No gitopsInstance Found`),
+			},
+		},
+	})
+}
+
+func TestSortInstanceChanges(t *testing.T) {
+	tests := map[string]struct {
+		timestamps []string
+		want       []string
+	}{
+		"sorted": {
+			timestamps: []string{"2024-05-01T10:00:00Z", "2024-05-02T10:00:00Z"},
+			want:       []string{"2024-05-01T10:00:00Z", "2024-05-02T10:00:00Z"},
+		},
+		"unsorted": {
+			timestamps: []string{"2024-05-02T10:00:00Z", "2024-05-01T12:00:00+02:00", "2024-05-01T10:00:00.5Z"},
+			want:       []string{"2024-05-01T12:00:00+02:00", "2024-05-01T10:00:00.5Z", "2024-05-02T10:00:00Z"},
+		},
+		"unparsable timestamp keeps the API order": {
+			timestamps: []string{"2024-05-02T10:00:00Z", "yesterday", "2024-05-01T10:00:00Z"},
+			want:       []string{"2024-05-02T10:00:00Z", "yesterday", "2024-05-01T10:00:00Z"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			changes := make([]instanceChange, 0, len(test.timestamps))
+			for _, timestamp := range test.timestamps {
+				changes = append(changes, instanceChange{Timestamp: timestamp})
+			}
+			sortInstanceChanges(changes)
+			got := make([]string, 0, len(changes))
+			for _, change := range changes {
+				got = append(got, change.Timestamp)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
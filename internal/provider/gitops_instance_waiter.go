@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Bounds of the exponential backoff between two stage polls.
+var (
+	stageWaitMinInterval = 2 * time.Second
+	stageWaitMaxInterval = 30 * time.Second
+)
+
+// Stages waited for when the practitioner does not configure any.
+var (
+	defaultTargetStages  = []string{"deployed"}
+	defaultFailureStages = []string{"failed"}
+)
+
+// stagedObject is an object rolled out by the GitOps pipeline through
+// stages, e.g. an instance or a manifest.
+type stagedObject interface {
+	// stageInfo returns the kind of object, its ID and its current stage.
+	stageInfo() (kind string, id string, stage string)
+}
+
+func (instance gitopsApiInstance) stageInfo() (string, string, string) {
+	return "instance", instance.Instance_id, instance.Stage
+}
+
+func (manifest gitopsApiManifest) stageInfo() (string, string, string) {
+	return "manifest", manifest.Manifest_id, manifest.Stage
+}
+
+// waitForInstanceStage polls the instance until its stage is one of
+// targetStages, see waitForStage.
+func waitForInstanceStage(ctx context.Context, client *gitopsApiClient, instance gitopsApiInstance, targetStages []string, failureStages []string) (gitopsApiInstance, error) {
+	return waitForStage(ctx, client.GetInstance, instance, targetStages, failureStages)
+}
+
+// waitForManifestStage polls the manifest until its stage is one of
+// targetStages, see waitForStage.
+func waitForManifestStage(ctx context.Context, client *gitopsApiClient, manifest gitopsApiManifest, targetStages []string, failureStages []string) (gitopsApiManifest, error) {
+	return waitForStage(ctx, client.GetManifest, manifest, targetStages, failureStages)
+}
+
+// waitForStage polls the object with get until its stage is one of
+// targetStages. It fails as soon as one of failureStages is reached or
+// ctx is done, reporting the last observed stage. Without targetStages
+// the object is returned right away.
+func waitForStage[T stagedObject](ctx context.Context, get func(context.Context, string) (T, error), object T, targetStages []string, failureStages []string) (T, error) {
+	interval := stageWaitMinInterval
+	for {
+		kind, id, stage := object.stageInfo()
+		if len(targetStages) == 0 || slices.Contains(targetStages, stage) {
+			return object, nil
+		}
+		if slices.Contains(failureStages, stage) {
+			return object, fmt.Errorf("%s %s reached failure stage %q", kind, id, stage)
+		}
+
+		tflog.Debug(ctx, "Waiting for gitops "+kind+" stage", map[string]any{
+			kind + "_id":    id,
+			"stage":         stage,
+			"target_stages": targetStages,
+			"next_poll":     interval.String(),
+		})
+
+		if err := sleep(ctx, interval); err != nil {
+			return object, stageTimeoutError(object, targetStages)
+		}
+		interval = min(interval*2, stageWaitMaxInterval)
+
+		polled, err := get(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return object, stageTimeoutError(object, targetStages)
+			}
+			return object, err
+		}
+		object = polled
+	}
+}
+
+// stageTimeoutError reports the last stage observed before giving up.
+func stageTimeoutError(object stagedObject, targetStages []string) error {
+	kind, id, stage := object.stageInfo()
+	return fmt.Errorf(
+		"timeout while waiting for %s %s to reach stage %s, last observed stage: %q",
+		kind, id, strings.Join(targetStages, " or "), stage,
+	)
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"maps"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// mergeLabels returns the default labels overridden by labels.
+func mergeLabels(defaults map[string]string, labels map[string]string) map[string]string {
+	merged := maps.Clone(defaults)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	maps.Copy(merged, labels)
+	return merged
+}
+
+// instanceLabels returns the labels attribute of an instance labelled with
+// all. Default labels are left out, unless configured holds them as well,
+// so they do not show up as configuration drift.
+func instanceLabels(ctx context.Context, all map[string]string, defaults map[string]string, configured types.Map) (types.Map, diag.Diagnostics) {
+	current := map[string]types.String{}
+	var diags diag.Diagnostics
+	if !configured.IsNull() && !configured.IsUnknown() {
+		diags = configured.ElementsAs(ctx, &current, false)
+	}
+
+	labels := map[string]string{}
+	for key, value := range all {
+		_, isConfigured := current[key]
+		if defaultValue, ok := defaults[key]; ok && defaultValue == value && !isConfigured {
+			continue
+		}
+		labels[key] = value
+	}
+	if len(labels) == 0 && configured.IsNull() {
+		return types.MapNull(types.StringType), diags
+	}
+	return labelsValue(labels), diags
+}
+
+// configuredLabels returns the known labels of labels. It reports false if
+// labels or any of its values are unknown.
+func configuredLabels(ctx context.Context, labels types.Map) (map[string]string, bool, diag.Diagnostics) {
+	if labels.IsUnknown() {
+		return nil, false, nil
+	}
+	elements := map[string]types.String{}
+	diags := labels.ElementsAs(ctx, &elements, false)
+	values := make(map[string]string, len(elements))
+	for key, value := range elements {
+		if value.IsUnknown() {
+			return nil, false, diags
+		}
+		values[key] = value.ValueString()
+	}
+	return values, true, diags
+}
+
+// labelsValue converts labels into a known map value.
+func labelsValue(labels map[string]string) types.Map {
+	elements := make(map[string]attr.Value, len(labels))
+	for key, value := range labels {
+		elements[key] = types.StringValue(value)
+	}
+	return types.MapValueMust(types.StringType, elements)
+}
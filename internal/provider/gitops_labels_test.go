@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestMergeLabels(t *testing.T) {
+	defaults := map[string]string{"team": "platform", "cost-center": "1234"}
+	got := mergeLabels(defaults, map[string]string{"team": "payments", "app": "redis"})
+	want := map[string]string{"team": "payments", "cost-center": "1234", "app": "redis"}
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if defaults["team"] != "platform" {
+		t.Error("default labels were modified")
+	}
+	if got := mergeLabels(nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("got %v without labels, want an empty map", got)
+	}
+}
+
+func TestInstanceLabels(t *testing.T) {
+	defaults := map[string]string{"team": "platform", "cost-center": "1234"}
+	for name, tc := range map[string]struct {
+		all        map[string]string
+		configured types.Map
+		want       types.Map
+	}{
+		"only default labels": {
+			all:        defaults,
+			configured: types.MapNull(types.StringType),
+			want:       types.MapNull(types.StringType),
+		},
+		"overridden default label": {
+			all:        map[string]string{"team": "payments", "cost-center": "1234"},
+			configured: labelsValue(map[string]string{"team": "payments"}),
+			want:       labelsValue(map[string]string{"team": "payments"}),
+		},
+		"default label configured with the default value": {
+			all:        defaults,
+			configured: labelsValue(map[string]string{"team": "platform"}),
+			want:       labelsValue(map[string]string{"team": "platform"}),
+		},
+		"label added outside of Terraform": {
+			all:        map[string]string{"team": "platform", "cost-center": "1234", "extra": "drift"},
+			configured: types.MapNull(types.StringType),
+			want:       labelsValue(map[string]string{"extra": "drift"}),
+		},
+		"empty labels": {
+			all:        defaults,
+			configured: labelsValue(map[string]string{}),
+			want:       labelsValue(map[string]string{}),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, diags := instanceLabels(context.Background(), tc.all, defaults, tc.configured)
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// sensitiveLogFieldKeys are the log field keys whose values are masked,
+// whatever they hold. code and device_code are the authorization code and
+// the device code redeemed at the token endpoint, which also masks the
+// error codes in traced Gitops API responses.
+var sensitiveLogFieldKeys = []string{
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"password",
+	"client_secret",
+	"subject_token",
+	"assertion",
+	"authorization",
+	"code",
+	"device_code",
+}
+
+// jwtPattern matches JWTs, like most access, refresh and ID tokens, in
+// log messages and field values.
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)
+
+// maskCredentials returns ctx masking the credentials of the client, JWTs
+// and the given secrets, e.g. opaque tokens, in all logs written with it.
+func (c *gitopsApiClient) maskCredentials(ctx context.Context, secrets ...string) context.Context {
+	ctx = tflog.MaskFieldValuesWithFieldKeys(ctx, sensitiveLogFieldKeys...)
+	ctx = tflog.MaskAllFieldValuesRegexes(ctx, jwtPattern)
+	ctx = tflog.MaskMessageRegexes(ctx, jwtPattern)
+	if values := c.secrets(secrets...); len(values) > 0 {
+		ctx = tflog.MaskAllFieldValuesStrings(ctx, values...)
+		ctx = tflog.MaskMessageStrings(ctx, values...)
+	}
+	return ctx
+}
+
+// logContext returns ctx for the logs of the client, masking its
+// credentials and the given secrets and naming its environment, if any.
+func (c *gitopsApiClient) logContext(ctx context.Context, secrets ...string) context.Context {
+	ctx = c.maskCredentials(ctx, secrets...)
+	if c.environment != "" {
+		ctx = tflog.SetField(ctx, "gitops_environment", c.environment)
+	}
+	return ctx
+}
+
+// secrets returns the configured credentials of the client and the given
+// secrets, leaving out empty ones, as masking an empty string would mask
+// everything.
+func (c *gitopsApiClient) secrets(secrets ...string) []string {
+	var values []string
+	for _, secret := range append(secrets, c.Password, c.ClientSecret, c.idToken) {
+		if secret != "" {
+			values = append(values, secret)
+		}
+	}
+	return values
+}
+
+// accessTokenMetadata returns log fields describing accessToken without
+// revealing it: its issuer, subject, expiry and scopes.
+func accessTokenMetadata(accessToken string) map[string]any {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, claims); err != nil {
+		return map[string]any{"token_format": "opaque"}
+	}
+
+	fields := map[string]any{"token_format": "jwt"}
+	if issuer, err := claims.GetIssuer(); err == nil && issuer != "" {
+		fields["token_issuer"] = issuer
+	}
+	if subject, err := claims.GetSubject(); err == nil && subject != "" {
+		fields["token_subject"] = subject
+	}
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		fields["token_expires_at"] = expiresAt.Format(time.RFC3339)
+	}
+	// Scopes are a space separated "scope" (RFC 8693) or a "scp" list
+	switch scopes := firstClaim(claims, "scope", "scp").(type) {
+	case string:
+		fields["token_scopes"] = scopes
+	case []any:
+		var names []string
+		for _, scope := range scopes {
+			if name, ok := scope.(string); ok {
+				names = append(names, name)
+			}
+		}
+		fields["token_scopes"] = strings.Join(names, " ")
+	}
+	return fields
+}
+
+// firstClaim returns the value of the first of keys present in claims.
+func firstClaim(claims jwt.MapClaims, keys ...string) any {
+	for _, key := range keys {
+		if value, ok := claims[key]; ok {
+			return value
+		}
+	}
+	return nil
+}
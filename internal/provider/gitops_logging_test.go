@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestMaskCredentials(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	client := newFakeApiClient(t, api, grantTypePassword)
+	client.idToken = "opaque-id-token"
+	accessToken := testAccessToken(t, time.Hour)
+
+	var out bytes.Buffer
+	ctx := client.maskCredentials(tflogtest.RootLogger(context.Background(), &out), "opaque-refresh-token")
+	tflog.Debug(ctx, "Token "+accessToken+" with password "+fakePassword, map[string]any{
+		"access_token": "opaque-access-token",
+		"error":        "invalid client secret " + fakeClientSecret + " or refresh token opaque-refresh-token",
+		"id_token":     client.idToken,
+		"header":       "Bearer " + accessToken,
+	})
+
+	for _, secret := range []string{accessToken, fakePassword, fakeClientSecret, "opaque-access-token", "opaque-refresh-token", client.idToken} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("secret %q was logged: %s", secret, out.String())
+		}
+	}
+}
+
+func TestGitopsApiClientLogsOnlyTokenMetadata(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.setAccessTokenTTL(tokenRefreshMargin / 2)
+	client := newFakeApiClient(t, api, grantTypePassword)
+	if err := client.getToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	api.setAccessTokenTTL(time.Hour)
+
+	var out bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &out)
+	if _, err := client.ListInstances(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refreshed map[string]any
+	for _, entry := range entries {
+		for key, value := range entry {
+			if s, ok := value.(string); ok && (strings.Contains(s, client.AccessToken) || strings.Contains(s, client.RefreshToken)) {
+				t.Errorf("token logged in %s: %v", key, entry)
+			}
+		}
+		if entry["@message"] == "Refreshed gitops access token" {
+			refreshed = entry
+		}
+	}
+	if refreshed == nil {
+		t.Fatalf("token refresh was not logged: %v", entries)
+	}
+	if refreshed["token_subject"] != fakeUsername || refreshed["token_issuer"] != "https://idp.example.com" || refreshed["token_expires_at"] == nil {
+		t.Errorf("got token metadata %v, want issuer, subject and expiry", refreshed)
+	}
+}
+
+func TestAccessTokenMetadata(t *testing.T) {
+	if fields := accessTokenMetadata("opaque-token"); fields["token_format"] != "opaque" || len(fields) != 1 {
+		t.Errorf("got %v for an opaque token, want only its format", fields)
+	}
+
+	fields := accessTokenMetadata(testAccessToken(t, time.Hour))
+	if fields["token_format"] != "jwt" || fields["token_subject"] != "terraform" {
+		t.Errorf("got %v, want the metadata of the JWT", fields)
+	}
+}
@@ -0,0 +1,404 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource                = &gitopsManifestResource{}
+	_ resource.ResourceWithConfigure   = &gitopsManifestResource{}
+	_ resource.ResourceWithImportState = &gitopsManifestResource{}
+)
+
+// NewGitopsManifestResource is a helper function to simplify the provider implementation.
+func NewGitopsManifestResource() resource.Resource {
+	return &gitopsManifestResource{}
+}
+
+// manifestOrderAttributes are the attributes sent to the Gitops API, used
+// to attach its validation errors to the configuration.
+var manifestOrderAttributes = []string{
+	"service_type",
+	"spec",
+}
+
+// gitopsManifestResource is the resource implementation.
+type gitopsManifestResource struct {
+	client *gitopsApiClient
+}
+
+// gitopsManifestResourceModel maps the resource schema data.
+type gitopsManifestResourceModel struct {
+	Manifest_id   types.String    `tfsdk:"manifest_id"`
+	Order_time    types.String    `tfsdk:"order_time"`
+	Service_type  types.String    `tfsdk:"service_type"`
+	Spec          jsonObjectValue `tfsdk:"spec"`
+	Stage         types.String    `tfsdk:"stage"`
+	Status        types.String    `tfsdk:"status"`
+	LastUpdated   types.String    `tfsdk:"last_updated"`
+	TargetStages  types.Set       `tfsdk:"target_stages"`
+	FailureStages types.Set       `tfsdk:"failure_stages"`
+	Timeouts      timeouts.Value  `tfsdk:"timeouts"`
+}
+
+// Configure adds the provider configured client to the resource.
+func (r *gitopsManifestResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Add a nil check when handling ProviderData because Terraform
+	// sets that data after it calls the ConfigureProvider RPC.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*gitopsApiClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *gitopsApiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+// Metadata returns the resource type name.
+func (r *gitopsManifestResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_manifest"
+}
+
+// Schema defines the schema for the resource.
+func (r *gitopsManifestResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages a GitOps managed object of any service type of the Gitops API catalog, described by a free-form spec",
+		Attributes: map[string]schema.Attribute{
+			"manifest_id": schema.StringAttribute{
+				Description: "ID of the Gitops manifest",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"order_time": schema.StringAttribute{
+				Description: "Order time of the Gitops manifest",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"service_type": schema.StringAttribute{
+				Description: "Service type of the Gitops manifest as listed in the Gitops API catalog, e.g. \"redis\". " +
+					"Changing it replaces the manifest.",
+				Required: true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"spec": schema.StringAttribute{
+				Description: "Spec of the Gitops manifest as JSON-encoded object, e.g. jsonencode({ replicas = 3 }). " +
+					"Its fields depend on the service_type and are validated by the Gitops API. " +
+					"Differences in formatting and key order are ignored.",
+				CustomType: jsonObjectType{},
+				Required:   true,
+				Validators: []validator.String{
+					jsonObjectValidator{},
+				},
+			},
+			"stage": schema.StringAttribute{
+				Description: "Stage of the Gitops manifest in the GitOps pipeline",
+				Computed:    true,
+			},
+			"status": schema.StringAttribute{
+				Description: "Status of the Gitops manifest rollout as reported by the Gitops API",
+				Computed:    true,
+			},
+			"last_updated": schema.StringAttribute{
+				Description: "Timestamp of last update",
+				Computed:    true,
+			},
+			"target_stages": schema.SetAttribute{
+				Description: "Stages the manifest has to reach before create and update complete. " +
+					"Set to an empty set to skip waiting. Defaults to [\"deployed\"].",
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     setdefault.StaticValue(stringSetValue(defaultTargetStages)),
+			},
+			"failure_stages": schema.SetAttribute{
+				Description: "Stages that fail create and update while waiting for target_stages. Defaults to [\"failed\"].",
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     setdefault.StaticValue(stringSetValue(defaultFailureStages)),
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+// Create a new resource.
+func (r *gitopsManifestResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	// Retrieve values from plan
+	var plan gitopsManifestResourceModel
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Generate API request body from plan
+	var manifest_order manifestOrder
+	manifest_order.Service_type = plan.Service_type.ValueString()
+	manifest_order.Spec = json.RawMessage(plan.Spec.ValueString())
+
+	// The order token is sent as idempotency key, so the Gitops API
+	// recognizes retried requests of this order, also by a later apply if
+	// the outcome of this one stays unknown.
+	orderToken, orderedAt, err := r.client.orderToken("manifest", manifest_order)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error creating gitopsManifest",
+			"Could not generate order token, unexpected error: "+err.Error(),
+		)
+		return
+	}
+
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	lookupCtx := ctx
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	gitopsManifest, err := r.client.PostManifestOrder(ctx, manifest_order, orderToken)
+	if err != nil && orderMayHaveSucceeded(err) {
+		// The order may have been placed even though its response got
+		// lost, adopt the manifest instead of ordering it again next time.
+		orderedSpec := newJsonObjectValue(string(manifest_order.Spec))
+		matches := func(manifest gitopsApiManifest) bool {
+			if manifest.Service_type != manifest_order.Service_type {
+				return false
+			}
+			equal, _ := orderedSpec.StringSemanticEquals(ctx, newJsonObjectValue(string(manifest.Spec)))
+			return equal
+		}
+		if manifest, found := adoptLostOrder(lookupCtx, "manifest", err, orderedAt, r.client.ListManifests, matches); found {
+			gitopsManifest, err = manifest, nil
+		}
+	}
+	if err == nil || !orderMayHaveSucceeded(err) {
+		// The order was placed or rejected, it must not be repeated
+		if settleErr := r.client.orderSettled("manifest", manifest_order); settleErr != nil {
+			tflog.Warn(ctx, "Could not remove order token", map[string]any{
+				"error": settleErr.Error(),
+			})
+		}
+	}
+	if err != nil {
+		addApiErrorDiagnostics(&resp.Diagnostics,
+			"Error creating gitopsManifest",
+			"Could not create gitopsManifest",
+			err, manifestOrderAttributes...,
+		)
+		return
+	}
+
+	// Wait for the GitOps pipeline to roll out the manifest. The manifest
+	// exists at this point, so it is saved to state even if waiting fails.
+	gitopsManifest, err = r.waitForStage(ctx, plan, gitopsManifest)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error waiting for gitopsManifest",
+			"Manifest "+gitopsManifest.Manifest_id+" was created but did not reach its target stage: "+err.Error(),
+		)
+	}
+
+	// Map response body to schema and populate Computed attribute values
+	plan.Manifest_id = types.StringValue(gitopsManifest.Manifest_id)
+	plan.Order_time = types.StringValue(gitopsManifest.Order_time)
+	plan.Stage = types.StringValue(gitopsManifest.Stage)
+	plan.Status = types.StringValue(gitopsManifest.Status)
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	// Set state to fully populated data
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Read resource information.
+func (r *gitopsManifestResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Get current state
+	var state gitopsManifestResourceModel
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Get refreshed manifest value from Gitops API
+	gitopsManifest, err := r.client.GetManifest(ctx, state.Manifest_id.ValueString())
+	if isNotFound(err) {
+		// The manifest was deleted outside of Terraform, drop it from
+		// state so Terraform plans to re-create it.
+		tflog.Warn(ctx, "gitops manifest not found, removing it from state", map[string]any{
+			"manifest_id": state.Manifest_id.ValueString(),
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addApiErrorDiagnostics(&resp.Diagnostics,
+			"Error Reading gitopsManifest",
+			"Could not read gitopsManifest ID "+state.Manifest_id.ValueString(),
+			err,
+		)
+		return
+	}
+
+	state.Manifest_id = types.StringValue(gitopsManifest.Manifest_id)
+	state.Order_time = types.StringValue(gitopsManifest.Order_time)
+	state.Service_type = types.StringValue(gitopsManifest.Service_type)
+	// The spec as formatted in the configuration is kept unless the
+	// Gitops API reports a different object, see jsonObjectValue.
+	state.Spec = newJsonObjectValue(string(gitopsManifest.Spec))
+	state.Stage = types.StringValue(gitopsManifest.Stage)
+	state.Status = types.StringValue(gitopsManifest.Status)
+
+	// Imported manifests have no waiter configuration yet
+	if state.TargetStages.IsNull() {
+		state.TargetStages = stringSetValue(defaultTargetStages)
+	}
+	if state.FailureStages.IsNull() {
+		state.FailureStages = stringSetValue(defaultFailureStages)
+	}
+
+	// Set refreshed state
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+func (r *gitopsManifestResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Retrieve values from plan
+	var plan gitopsManifestResourceModel
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Generate API request body from plan
+	var manifest_update manifestUpdate
+	manifest_update.Spec = json.RawMessage(plan.Spec.ValueString())
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	gitopsManifest, err := r.client.PutManifest(ctx, plan.Manifest_id.ValueString(), manifest_update)
+	if err != nil {
+		addApiErrorDiagnostics(&resp.Diagnostics,
+			"Error Updating gitopsManifest",
+			"Could not update manifest",
+			err, manifestOrderAttributes...,
+		)
+		return
+	}
+
+	gitopsManifest, err = r.waitForStage(ctx, plan, gitopsManifest)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error waiting for gitopsManifest",
+			"Manifest "+gitopsManifest.Manifest_id+" was updated but did not reach its target stage: "+err.Error(),
+		)
+	}
+	plan.Stage = types.StringValue(gitopsManifest.Stage)
+	plan.Status = types.StringValue(gitopsManifest.Status)
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+func (r *gitopsManifestResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Retrieve values from state
+	var state gitopsManifestResourceModel
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	err := r.client.DeleteManifest(ctx, state.Manifest_id.ValueString())
+	if isNotFound(err) {
+		// Already gone, nothing left to delete
+		return
+	}
+	if err != nil {
+		addApiErrorDiagnostics(&resp.Diagnostics,
+			"Error Deleting gitopsManifest "+state.Manifest_id.ValueString(),
+			"Could not delete gitopsManifest",
+			err,
+		)
+		return
+	}
+}
+
+func (r *gitopsManifestResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// Retrieve import ID and save to id attribute
+	resource.ImportStatePassthroughID(ctx, path.Root("manifest_id"), req, resp)
+}
+
+// waitForStage waits for the manifest to reach one of the planned target stages.
+func (r *gitopsManifestResource) waitForStage(ctx context.Context, plan gitopsManifestResourceModel, manifest gitopsApiManifest) (gitopsApiManifest, error) {
+	var targetStages, failureStages []string
+	plan.TargetStages.ElementsAs(ctx, &targetStages, false)
+	plan.FailureStages.ElementsAs(ctx, &failureStages, false)
+	return waitForManifestStage(ctx, r.client, manifest, targetStages, failureStages)
+}
@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// testAccManifestConfig returns a gitops_manifest of serviceType with
+// replicaCount replicas in its spec. The spec is formatted differently
+// than the Gitops API returns it.
+func testAccManifestConfig(serviceType string, replicaCount int) string {
+	return fmt.Sprintf(`
+resource "gitops_manifest" "test" {
+  service_type = %[1]q
+  spec         = <<-EOT
+    {
+      "storage": { "size": "1Gi" },
+      "replicas": %[2]d
+    }
+  EOT
+}
+`, serviceType, replicaCount)
+}
+
+// testAccCaptureManifestId stores the manifest_id of resourceName in id.
+func testAccCaptureManifestId(resourceName string, id *string) resource.TestCheckFunc {
+	return resource.TestCheckResourceAttrWith(resourceName, "manifest_id", func(value string) error {
+		*id = value
+		return nil
+	})
+}
+
+// testAccCheckManifestDestroyed checks that no manifest is left in api.
+func testAccCheckManifestDestroyed(api *fakeGitopsApi) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		if count := api.manifestCount(); count != 0 {
+			return fmt.Errorf("got %d manifests left, want all destroyed", count)
+		}
+		return nil
+	}
+}
+
+// testAccCheckManifestSpec checks the spec of the manifest with id in api.
+func testAccCheckManifestSpec(api *fakeGitopsApi, id *string, want map[string]any) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		manifest, ok := api.manifest(*id)
+		if !ok {
+			return fmt.Errorf("manifest %s not found", *id)
+		}
+		var spec map[string]any
+		if err := json.Unmarshal(manifest.Spec, &spec); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(spec, want) {
+			return fmt.Errorf("got spec %v, want %v", spec, want)
+		}
+		return nil
+	}
+}
+
+func TestAccGitopsManifestResource(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir())
+	var manifestId string
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckManifestDestroyed(api),
+		Steps: []resource.TestStep{
+			// Create and Read testing, the reformatted spec returned by
+			// the Gitops API must not cause a diff
+			{
+				Config: config + testAccManifestConfig("redis", 1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("gitops_manifest.test", "manifest_id"),
+					resource.TestCheckResourceAttrSet("gitops_manifest.test", "order_time"),
+					resource.TestCheckResourceAttrSet("gitops_manifest.test", "last_updated"),
+					resource.TestCheckResourceAttr("gitops_manifest.test", "service_type", "redis"),
+					resource.TestCheckResourceAttr("gitops_manifest.test", "stage", "deployed"),
+					resource.TestCheckResourceAttr("gitops_manifest.test", "status", "synced"),
+					testAccCaptureManifestId("gitops_manifest.test", &manifestId),
+					testAccCheckManifestSpec(api, &manifestId, map[string]any{
+						"replicas": 1.0,
+						"storage":  map[string]any{"size": "1Gi"},
+					}),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:                         "gitops_manifest.test",
+				ImportState:                          true,
+				ImportStateVerify:                    true,
+				ImportStateIdFunc:                    func(*terraform.State) (string, error) { return manifestId, nil },
+				ImportStateVerifyIdentifierAttribute: "manifest_id",
+				// Imported specs are formatted as returned by the Gitops
+				// API, which is semantically equal.
+				ImportStateVerifyIgnore: []string{"last_updated", "spec"},
+			},
+			// Update and Read testing
+			{
+				Config: config + testAccManifestConfig("redis", 3),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_manifest.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPtr("gitops_manifest.test", "manifest_id", &manifestId),
+					resource.TestCheckResourceAttr("gitops_manifest.test", "stage", "deployed"),
+					testAccCheckManifestSpec(api, &manifestId, map[string]any{
+						"replicas": 3.0,
+						"storage":  map[string]any{"size": "1Gi"},
+					}),
+				),
+			},
+			// Drift of the spec changed outside of Terraform
+			{
+				PreConfig: func() {
+					api.modifyManifest(manifestId, func(manifest *gitopsApiManifest) {
+						manifest.Spec = json.RawMessage(`{"replicas":7,"storage":{"size":"1Gi"}}`)
+					})
+				},
+				Config: config + testAccManifestConfig("redis", 3),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_manifest.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: testAccCheckManifestSpec(api, &manifestId, map[string]any{
+					"replicas": 3.0,
+					"storage":  map[string]any{"size": "1Gi"},
+				}),
+			},
+			// Changing the service type replaces the manifest
+			{
+				Config: config + testAccManifestConfig("postgres", 3),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_manifest.test", plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+				Check: resource.TestCheckResourceAttr("gitops_manifest.test", "service_type", "postgres"),
+			},
+		},
+	})
+}
+
+func TestAccGitopsManifestResourceRejectsInvalidSpec(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, t.TempDir()) +
+					strings.Replace(testAccManifestConfig("redis", 1), `"replicas"`, `replicas`, 1),
+				ExpectError: regexp.MustCompile(`Invalid JSON Object`),
+			},
+			{
+				Config: testAccProviderConfig(api, t.TempDir()) + `
+resource "gitops_manifest" "test" {
+  service_type = "redis"
+  spec         = jsonencode(["not", "an", "object"])
+}
+`,
+				ExpectError: regexp.MustCompile(`Invalid JSON Object`),
+			},
+		},
+	})
+}
+
+func TestAccGitopsManifestResourceAdoptsLostOrder(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	// Lose the responses of the order and both of its retries
+	for range 3 {
+		api.dropNext(http.MethodPost, "/manifests")
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckManifestDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, t.TempDir()) + testAccManifestConfig("redis", 1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("gitops_manifest.test", "manifest_id"),
+					resource.TestCheckResourceAttr("gitops_manifest.test", "stage", "deployed"),
+					func(_ *terraform.State) error {
+						if count := api.manifestCount(); count != 1 {
+							return fmt.Errorf("got %d manifests, want the lost order adopted", count)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,332 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// OAuth2 grant types supported for grant_type.
+const (
+	grantTypePassword          = "password"
+	grantTypeAuthCode          = "auth_code"
+	grantTypeDeviceCode        = "device_code"
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeTokenExchange     = "token_exchange"
+	grantTypeJwtBearer         = "jwt_bearer"
+)
+
+// Grant and token type URIs of RFC 8693 token exchange and the RFC 7523
+// JWT bearer assertion grant.
+const (
+	tokenExchangeGrantUri = "urn:ietf:params:oauth:grant-type:token-exchange"
+	jwtBearerGrantUri     = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	idTokenTypeUri        = "urn:ietf:params:oauth:token-type:id_token"
+	accessTokenTypeUri    = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// grantTypes are the supported values of grant_type.
+var grantTypes = []string{
+	grantTypePassword,
+	grantTypeAuthCode,
+	grantTypeDeviceCode,
+	grantTypeClientCredentials,
+	grantTypeTokenExchange,
+	grantTypeJwtBearer,
+}
+
+// idTokenGrantTypes are the grant types authenticating with an ID token
+// of the workload, e.g. the OIDC ID token of a CI job.
+var idTokenGrantTypes = []string{
+	grantTypeTokenExchange,
+	grantTypeJwtBearer,
+}
+
+// interactiveGrantTypes are the grant types authenticating the user in a
+// browser. They only run when the provider is configured, their access
+// tokens are renewed with the refresh token.
+var interactiveGrantTypes = []string{
+	grantTypeAuthCode,
+	grantTypeDeviceCode,
+}
+
+// authCodeFlowStarted is set once the auth_code grant of gitopsclient ran
+// in this process. It registers its redirect handler on the global
+// http.DefaultServeMux, which panics on a second registration.
+var authCodeFlowStarted atomic.Bool
+
+// tokenRefreshMargin is how long before its expiry an access token is
+// renewed, leaving time for the request to reach the Gitops API.
+const tokenRefreshMargin = time.Minute
+
+// minStaticAccessTokenValidity is the minimum remaining lifetime of a
+// pre-issued access token, which cannot be renewed while Terraform runs.
+const minStaticAccessTokenValidity = 15 * time.Minute
+
+// tokenResponse is the successful response of an OAuth2 token endpoint.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// getToken obtains a new access token with the configured grant type and
+// caches it. The interactive grants are left to gitopsclient.
+func (c *gitopsApiClient) getToken(ctx context.Context) error {
+	switch c.GrantType {
+	case grantTypeClientCredentials:
+		return c.requestToken(ctx, map[string]string{
+			"grant_type":    grantTypeClientCredentials,
+			"client_secret": c.ClientSecret,
+		})
+	case grantTypeTokenExchange, grantTypeJwtBearer:
+		return c.idTokenGrant(ctx)
+	default:
+		return c.upstreamGetToken()
+	}
+}
+
+// upstreamGetToken runs the grant of gitopsclient. gitopsclient writes the
+// tokens to CachePath without restricting their permissions, so it is
+// pointed to a private temporary directory and the tokens are cached by
+// the token cache instead.
+func (c *gitopsApiClient) upstreamGetToken() error {
+	if c.GrantType == grantTypeAuthCode && !authCodeFlowStarted.CompareAndSwap(false, true) {
+		return errors.New("the auth_code grant can only run once per Terraform run, " +
+			"configure all provider aliases with the same OAuth2 settings to share its tokens or use another grant_type")
+	}
+	tmpDir, err := os.MkdirTemp("", "gitops-token-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cachePath := c.CachePath
+	c.CachePath = tmpDir
+	err = c.GetToken()
+	c.CachePath = cachePath
+	if err != nil {
+		return err
+	}
+	return c.storeTokens()
+}
+
+// idTokenGrant exchanges the ID token of the workload for an access token.
+// The ID token file is read on every call, as CI systems replace short
+// lived ID tokens during long jobs.
+func (c *gitopsApiClient) idTokenGrant(ctx context.Context) error {
+	idToken := c.idToken
+	if c.idTokenFile != "" {
+		content, err := os.ReadFile(c.idTokenFile)
+		if err != nil {
+			return fmt.Errorf("could not read id_token_file: %w", err)
+		}
+		idToken = strings.TrimSpace(string(content))
+	}
+	if idToken == "" {
+		return errors.New("the ID token is empty")
+	}
+
+	form := map[string]string{}
+	if c.GrantType == grantTypeTokenExchange {
+		form["grant_type"] = tokenExchangeGrantUri
+		form["subject_token"] = idToken
+		form["subject_token_type"] = idTokenTypeUri
+		form["requested_token_type"] = accessTokenTypeUri
+	} else {
+		form["grant_type"] = jwtBearerGrantUri
+		form["assertion"] = idToken
+	}
+	if c.ClientSecret != "" {
+		form["client_secret"] = c.ClientSecret
+	}
+	return c.requestToken(ctx, form)
+}
+
+// requestToken requests an access token from the token endpoint with the
+// grant specific form values and caches it. The client_id and scopes are
+// added to form.
+func (c *gitopsApiClient) requestToken(ctx context.Context, form map[string]string) error {
+	form["client_id"] = c.ClientId
+	if c.Scopes != "" {
+		form["scope"] = c.Scopes
+	}
+
+	var token tokenResponse
+	resp, err := c.RestyClient.R().
+		SetContext(ctx).
+		SetFormData(form).
+		SetResult(&token).
+		Post(c.TokenURI)
+	if err != nil {
+		return err
+	}
+	if err := c.handleResponse(resp); err != nil {
+		return err
+	}
+	if token.AccessToken == "" {
+		return errors.New("token endpoint " + c.TokenURI + " returned no access_token")
+	}
+
+	c.AccessToken = token.AccessToken
+	// Refresh responses only contain a refresh token if it was rotated
+	if token.RefreshToken != "" || form["grant_type"] != "refresh_token" {
+		c.RefreshToken = token.RefreshToken
+	}
+	return c.storeTokens()
+}
+
+// accessToken returns a valid access token, renewing the current one if
+// it expires within tokenRefreshMargin. Renewals are serialized, within
+// the process by tokenMu and across processes by the token cache lock, so
+// an expiring token is only renewed once and the others adopt the renewed
+// token from the cache.
+func (c *gitopsApiClient) accessToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	ctx = c.logContext(ctx, c.AccessToken, c.RefreshToken)
+
+	if c.staticAccessToken || !c.accessTokenExpiring() {
+		c.authenticated = true
+		return c.AccessToken, nil
+	}
+
+	unlock, err := c.tokenCache.lock()
+	if err != nil {
+		return "", fmt.Errorf("could not lock the token cache: %w", err)
+	}
+	defer unlock()
+
+	if !c.loadTokens(ctx) {
+		if err := c.renewAccessToken(ctx); err != nil {
+			return "", err
+		}
+	}
+	c.authenticated = true
+	return c.AccessToken, nil
+}
+
+// accessTokenExpiring reports whether the current access token is missing
+// or expires within tokenRefreshMargin. Tokens without a readable expiry
+// are renewed once the Gitops API rejects them.
+func (c *gitopsApiClient) accessTokenExpiring() bool {
+	if c.AccessToken == "" {
+		return true
+	}
+	expiresAt, ok, err := accessTokenExpiry(c.AccessToken)
+	return err == nil && ok && time.Until(expiresAt) < tokenRefreshMargin
+}
+
+// invalidateAccessToken drops accessToken after the Gitops API rejected
+// it, unless a concurrent request already renewed it.
+func (c *gitopsApiClient) invalidateAccessToken(accessToken string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.rejectedAccessToken = accessToken
+	if c.AccessToken == accessToken {
+		c.AccessToken = ""
+	}
+}
+
+// renewAccessToken obtains a new access token with the refresh token, or
+// with the configured grant type if there is none or it is not accepted.
+// Interactive grants are only run for the first access token, as the user
+// cannot authenticate while Terraform applies.
+func (c *gitopsApiClient) renewAccessToken(ctx context.Context) error {
+	if c.RefreshToken != "" {
+		form := map[string]string{
+			"grant_type":    "refresh_token",
+			"refresh_token": c.RefreshToken,
+		}
+		if c.ClientSecret != "" {
+			form["client_secret"] = c.ClientSecret
+		}
+		err := c.requestToken(ctx, form)
+		if err == nil {
+			tflog.Debug(ctx, "Refreshed gitops access token", accessTokenMetadata(c.AccessToken))
+			return nil
+		}
+		tflog.Debug(ctx, "Could not refresh gitops access token, requesting a new one", map[string]any{
+			"error": err.Error(),
+		})
+	}
+	if c.authenticated && slices.Contains(interactiveGrantTypes, c.GrantType) {
+		return fmt.Errorf("the gitops access token expired and could not be refreshed, "+
+			"the %s grant cannot authenticate again while Terraform runs. Re-authenticate by running Terraform again", c.GrantType)
+	}
+	if err := c.getToken(ctx); err != nil {
+		return err
+	}
+	fields := accessTokenMetadata(c.AccessToken)
+	fields["grant_type"] = c.GrantType
+	tflog.Debug(ctx, "Requested new gitops access token", fields)
+	return nil
+}
+
+// storeTokens caches the current access and refresh token.
+func (c *gitopsApiClient) storeTokens() error {
+	return c.tokenCache.store(cachedTokens{
+		AccessToken:  c.AccessToken,
+		RefreshToken: c.RefreshToken,
+	})
+}
+
+// loadTokens adopts the cached tokens, which another provider
+// configuration or Terraform process may have renewed, and reports whether
+// the cached access token is usable. With a jwks_uri the access token
+// signature is verified, without one only its expiry is checked and the
+// signature is left to the Gitops API.
+func (c *gitopsApiClient) loadTokens(ctx context.Context) bool {
+	cached, err := c.tokenCache.load()
+	if err != nil {
+		tflog.Warn(ctx, "Could not read the gitops token cache", map[string]any{
+			"error": err.Error(),
+		})
+		return false
+	}
+	// The cached refresh token is the most recent one, refresh tokens may
+	// be rotated by every refresh
+	if cached.RefreshToken != "" {
+		c.RefreshToken = cached.RefreshToken
+	}
+	if cached.AccessToken == "" || cached.AccessToken == c.rejectedAccessToken {
+		return false
+	}
+	if c.JwksURI != "" {
+		if _, err := c.ParseToken(cached.AccessToken); err != nil {
+			tflog.Debug(ctx, "Cached gitops access token is invalid", map[string]any{
+				"error": err.Error(),
+			})
+			return false
+		}
+	}
+	expiresAt, ok, err := accessTokenExpiry(cached.AccessToken)
+	if err != nil || (ok && time.Until(expiresAt) < tokenRefreshMargin) {
+		return false
+	}
+	c.AccessToken = cached.AccessToken
+	return true
+}
+
+// accessTokenExpiry returns the expiry of a JWT access token. Tokens
+// without expiry are reported as not ok.
+func accessTokenExpiry(accessToken string) (time.Time, bool, error) {
+	token, _, err := jwt.NewParser().ParseUnverified(accessToken, jwt.MapClaims{})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	expiresAt, err := token.Claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return time.Time{}, false, err
+	}
+	return expiresAt.Time, true, nil
+}
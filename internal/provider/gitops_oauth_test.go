@@ -0,0 +1,248 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chillout2k/gitopsclient"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newFakeApiClient returns a client for api obtaining tokens with grantType,
+// caching them in a new disk token cache.
+func newFakeApiClient(t *testing.T, api *fakeGitopsApi, grantType string) *gitopsApiClient {
+	t.Helper()
+	return newFakeApiClientWithCache(t, api, grantType, t.TempDir())
+}
+
+// newFakeApiClientWithCache returns a client for api obtaining tokens with
+// grantType, caching them on disk in cachePath.
+func newFakeApiClientWithCache(t *testing.T, api *fakeGitopsApi, grantType string, cachePath string) *gitopsApiClient {
+	t.Helper()
+	client, err := gitopsclient.NewGitopsClient(gitopsclient.GitopsClientConfig{
+		GitopsApiURI: api.URL,
+		CachePath:    cachePath,
+		TokenURI:     api.URL + "/token",
+		GrantType:    grantType,
+		ClientId:     fakeClientId,
+		ClientSecret: fakeClientSecret,
+		Username:     fakeUsername,
+		Password:     fakePassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiClient := newGitopsApiClient(client, testRetryPolicy)
+	apiClient.tokenCache = newTokenCache(cacheModeDisk, cachePath, tokenCacheIdentity{
+		Issuer:    client.TokenURI,
+		ClientId:  client.ClientId,
+		Audience:  client.GitopsApiURI,
+		GrantType: grantType,
+		Username:  client.Username,
+	})
+	return apiClient
+}
+
+func TestIdTokenGrantRereadsIdTokenFile(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	idTokenFile := filepath.Join(t.TempDir(), "id_token")
+	apiClient := newFakeApiClient(t, api, grantTypeTokenExchange)
+	apiClient.idTokenFile = idTokenFile
+
+	for _, job := range []string{"job-1", "job-2"} {
+		idToken, err := signTestToken(job, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(idTokenFile, []byte(idToken+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := apiClient.getToken(context.Background()); err != nil {
+			t.Fatalf("%s: unexpected error: %s", job, err)
+		}
+		token, _, err := jwt.NewParser().ParseUnverified(apiClient.AccessToken, jwt.MapClaims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if subject, _ := token.Claims.GetSubject(); subject != job {
+			t.Errorf("got access token for %q, want %q", subject, job)
+		}
+	}
+}
+
+func TestAccessTokenExpiry(t *testing.T) {
+	expiresAt, ok, err := accessTokenExpiry(testAccessToken(t, time.Hour))
+	if err != nil || !ok {
+		t.Fatalf("got (%t, %v), want the expiry", ok, err)
+	}
+	if until := time.Until(expiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("got expiry in %s, want in 1h", until)
+	}
+
+	withoutExpiry, err := jwt.New(jwt.SigningMethodHS256).SignedString([]byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := accessTokenExpiry(withoutExpiry); ok || err != nil {
+		t.Errorf("got (%t, %v) for a token without expiry, want no expiry", ok, err)
+	}
+
+	if _, _, err := accessTokenExpiry("opaque-token"); err == nil {
+		t.Error("expected an error for a token that is no JWT")
+	}
+}
+
+func TestGitopsApiClientRefreshesExpiringToken(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.setAccessTokenTTL(tokenRefreshMargin / 2)
+	client := newFakeApiClient(t, api, grantTypePassword)
+	if err := client.getToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expiring := client.AccessToken
+	api.setAccessTokenTTL(time.Hour)
+
+	if _, err := client.ListInstances(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if api.refreshedTokens() != 1 {
+		t.Errorf("got %d refreshed tokens, want 1", api.refreshedTokens())
+	}
+	if client.AccessToken == expiring {
+		t.Error("expiring access token was not replaced")
+	}
+	cached, err := client.tokenCache.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.AccessToken != client.AccessToken {
+		t.Error("refreshed access token was not cached")
+	}
+}
+
+func TestGitopsApiClientSerializesTokenRenewal(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.setAccessTokenTTL(tokenRefreshMargin / 2)
+	client := newFakeApiClient(t, api, grantTypePassword)
+	if err := client.getToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	api.setAccessTokenTTL(time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.ListInstances(context.Background()); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if api.refreshedTokens() != 1 {
+		t.Errorf("got %d refreshed tokens, want 1", api.refreshedTokens())
+	}
+}
+
+func TestGitopsApiClientRenewsRejectedToken(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	client := newFakeApiClient(t, api, grantTypePassword)
+	if err := client.getToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	api.failNext(http.MethodGet, "/instances", http.StatusUnauthorized, map[string]string{"detail": "Token revoked"})
+
+	if _, err := client.ListInstances(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if api.refreshedTokens() != 1 {
+		t.Errorf("got %d refreshed tokens, want 1", api.refreshedTokens())
+	}
+}
+
+func TestGitopsApiClientRerunsGrantWithoutRefreshToken(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.setAccessTokenTTL(tokenRefreshMargin / 2)
+	client := newFakeApiClient(t, api, grantTypeClientCredentials)
+	if err := client.getToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	api.setAccessTokenTTL(time.Hour)
+
+	if _, err := client.ListInstances(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if api.issuedTokens() != 2 || api.refreshedTokens() != 0 {
+		t.Errorf("got %d issued and %d refreshed tokens, want 2 issued by the client credentials grant",
+			api.issuedTokens(), api.refreshedTokens())
+	}
+}
+
+func TestGitopsApiClientAdoptsTokenRenewedByOtherProcess(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.setAccessTokenTTL(tokenRefreshMargin / 2)
+	cachePath := t.TempDir()
+	first := newFakeApiClientWithCache(t, api, grantTypePassword, cachePath)
+	if err := first.getToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The second client stands in for another Terraform process sharing
+	// the cache, holding the same expiring token
+	second := newFakeApiClientWithCache(t, api, grantTypePassword, cachePath)
+	second.AccessToken = first.AccessToken
+	second.RefreshToken = first.RefreshToken
+	api.setAccessTokenTTL(time.Hour)
+
+	for _, client := range []*gitopsApiClient{first, second} {
+		if _, err := client.ListInstances(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if api.refreshedTokens() != 1 {
+		t.Errorf("got %d refreshed tokens, want 1", api.refreshedTokens())
+	}
+	if second.AccessToken != first.AccessToken {
+		t.Error("renewed access token was not adopted from the cache")
+	}
+}
+
+func TestGitopsApiClientDoesNotRerunInteractiveGrant(t *testing.T) {
+	for _, refreshToken := range []string{"", "revoked"} {
+		api := newFakeGitopsApi(t)
+		client := newFakeApiClient(t, api, grantTypeAuthCode)
+		client.AccessToken = testAccessToken(t, time.Hour)
+		client.RefreshToken = refreshToken
+		if _, err := client.ListInstances(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		api.failNext(http.MethodGet, "/instances", http.StatusUnauthorized, map[string]string{"detail": "Token revoked"})
+
+		_, err := client.ListInstances(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "Re-authenticate") {
+			t.Errorf("refresh token %q: got error %v, want the user asked to re-authenticate", refreshToken, err)
+		}
+		if api.issuedTokens() != 0 {
+			t.Errorf("refresh token %q: got %d issued tokens, want none", refreshToken, api.issuedTokens())
+		}
+	}
+}
+
+func TestGitopsApiClientRunsAuthCodeGrantOnce(t *testing.T) {
+	started := authCodeFlowStarted.Swap(true)
+	t.Cleanup(func() { authCodeFlowStarted.Store(started) })
+	api := newFakeGitopsApi(t)
+	client := newFakeApiClient(t, api, grantTypeAuthCode)
+
+	if _, err := client.accessToken(context.Background()); err == nil || !strings.Contains(err.Error(), "only run once") {
+		t.Errorf("got error %v, want the auth_code grant to not run twice", err)
+	}
+}
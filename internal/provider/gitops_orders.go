@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// pendingOrderTTL is how long the token of a pending order is reused.
+// Older tokens are replaced, as the Gitops API may have forgotten them.
+const pendingOrderTTL = 24 * time.Hour
+
+// orderedObject is an object placed by an order, see adoptLostOrder.
+type orderedObject interface {
+	stagedObject
+	// orderTime returns the time the object was ordered as reported by
+	// the Gitops API.
+	orderTime() string
+}
+
+func (instance gitopsApiInstance) orderTime() string {
+	return instance.Order_time
+}
+
+func (manifest gitopsApiManifest) orderTime() string {
+	return manifest.Order_time
+}
+
+// orderToken returns the idempotency key for an order of kind, e.g.
+// "instance", and the time the order was first attempted with it. With a
+// cache_path the key is kept there until orderSettled is called, so a
+// later apply repeating an order whose outcome is unknown sends the same
+// key and the Gitops API returns the object of the first order instead of
+// placing a duplicate.
+func (c *gitopsApiClient) orderToken(kind string, order any) (string, time.Time, error) {
+	path, err := c.pendingOrderPath(kind, order)
+	if err != nil || path == "" {
+		token, err := newOrderToken()
+		return token, time.Now(), err
+	}
+
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < pendingOrderTTL {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if token := strings.TrimSpace(string(content)); token != "" {
+			return token, info.ModTime(), nil
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", time.Time{}, err
+	}
+
+	token, err := newOrderToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, time.Now(), os.WriteFile(path, []byte(token), 0600)
+}
+
+// orderSettled forgets the token of an order of kind once its outcome is
+// known: the object was saved to state or the order was rejected.
+func (c *gitopsApiClient) orderSettled(kind string, order any) error {
+	path, err := c.pendingOrderPath(kind, order)
+	if err != nil || path == "" {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// pendingOrderPath returns the file the token of a pending order of kind
+// is kept in, named after the Gitops API and the order. It is empty
+// without a cache_path.
+func (c *gitopsApiClient) pendingOrderPath(kind string, order any) (string, error) {
+	if c.ordersPath == "" {
+		return "", nil
+	}
+	encoded, err := json.Marshal(order)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{c.GitopsApiURI, c.environment, kind, string(encoded)}, "\x00")))
+	return filepath.Join(c.ordersPath, kind+"-"+hex.EncodeToString(sum[:16])+".token"), nil
+}
+
+// orderMayHaveSucceeded reports whether an order that failed
+// with err may still have been placed: it was sent but its response was
+// lost, it failed on the server side or it conflicts with an earlier order.
+func orderMayHaveSucceeded(err error) bool {
+	var requestErr *gitopsRequestError
+	if errors.As(err, &requestErr) {
+		return true
+	}
+	var apiErr *gitopsApiError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusConflict || apiErr.StatusCode >= 500)
+}
+
+// adoptLostOrder looks for the object of kind placed by an order that
+// failed with orderErr: the single object listed by list that matches the
+// order and was ordered since orderedAt, the first attempt of the order.
+// Objects with an order time that cannot be parsed are considered recent.
+func adoptLostOrder[T orderedObject](ctx context.Context, kind string, orderErr error, orderedAt time.Time, list func(context.Context) ([]T, error), matches func(T) bool) (T, bool) {
+	var adopted T
+	objects, err := list(ctx)
+	if err != nil {
+		tflog.Warn(ctx, "Could not look up possibly ordered gitops "+kind, map[string]any{
+			"error": err.Error(),
+		})
+		return adopted, false
+	}
+
+	found := 0
+	for _, object := range objects {
+		if !matches(object) {
+			continue
+		}
+		// Allow for some clock skew between provider and Gitops API
+		orderTime, err := time.Parse(time.RFC3339, object.orderTime())
+		if err == nil && orderTime.Before(orderedAt.Add(-time.Minute)) {
+			continue
+		}
+		adopted = object
+		found++
+	}
+	if found != 1 {
+		var zero T
+		return zero, false
+	}
+
+	_, id, _ := adopted.stageInfo()
+	tflog.Warn(ctx, "Adopting gitops "+kind+" ordered by a failed request", map[string]any{
+		kind + "_id": id,
+		"error":      orderErr.Error(),
+	})
+	return adopted, true
+}
+
+// newOrderToken returns a random token identifying an order.
+func newOrderToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOrderMayHaveSucceeded(t *testing.T) {
+	respond := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			writeJSONStatus(w, status, map[string]any{"message": http.StatusText(status)})
+		}
+	}
+	drop := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	})
+
+	for name, test := range map[string]struct {
+		client func(t *testing.T) *gitopsApiClient
+		ctx    func() context.Context
+		want   bool
+	}{
+		"lost response": {
+			client: func(t *testing.T) *gitopsApiClient { return newTestApiClient(t, drop, testRetryPolicy) },
+			want:   true,
+		},
+		"conflict": {
+			client: func(t *testing.T) *gitopsApiClient {
+				return newTestApiClient(t, respond(http.StatusConflict), testRetryPolicy)
+			},
+			want: true,
+		},
+		"server error": {
+			client: func(t *testing.T) *gitopsApiClient {
+				return newTestApiClient(t, respond(http.StatusInternalServerError), testRetryPolicy)
+			},
+			want: true,
+		},
+		"rejected": {
+			client: func(t *testing.T) *gitopsApiClient {
+				return newTestApiClient(t, respond(http.StatusUnprocessableEntity), testRetryPolicy)
+			},
+		},
+		"unreachable": {
+			client: func(t *testing.T) *gitopsApiClient {
+				client := newTestApiClient(t, respond(http.StatusCreated), testRetryPolicy)
+				server := httptest.NewServer(http.NotFoundHandler())
+				server.Close()
+				client.GitopsApiURI = server.URL
+				return client
+			},
+		},
+		"canceled": {
+			client: func(t *testing.T) *gitopsApiClient {
+				return newTestApiClient(t, respond(http.StatusCreated), testRetryPolicy)
+			},
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+		},
+		"no access token": {
+			client: func(t *testing.T) *gitopsApiClient {
+				client := newTestApiClient(t, respond(http.StatusCreated), testRetryPolicy)
+				client.AccessToken = testAccessToken(t, -time.Hour)
+				return client
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.ctx != nil {
+				ctx = test.ctx()
+			}
+			_, err := test.client(t).PostInstanceOrder(ctx, instanceOrder{}, "order-1")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := orderMayHaveSucceeded(err); got != test.want {
+				t.Errorf("got %t for %q, want %t", got, err, test.want)
+			}
+		})
+	}
+}
@@ -2,16 +2,24 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
-	"github.com/chillout2k/gitopsclient"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure the implementation satisfies the expected interfaces.
@@ -19,6 +27,7 @@ var (
 	_ resource.Resource                = &gitopsInstanceResource{}
 	_ resource.ResourceWithConfigure   = &gitopsInstanceResource{}
 	_ resource.ResourceWithImportState = &gitopsInstanceResource{}
+	_ resource.ResourceWithModifyPlan  = &gitopsInstanceResource{}
 )
 
 // NewGitopsInstanceResource is a helper function to simplify the provider implementation.
@@ -26,24 +35,56 @@ func NewGitopsInstanceResource() resource.Resource {
 	return &gitopsInstanceResource{}
 }
 
+// Default timeouts of the instance operations, including stage waiting.
+const (
+	defaultCreateTimeout = 30 * time.Minute
+	defaultUpdateTimeout = 30 * time.Minute
+	defaultDeleteTimeout = 10 * time.Minute
+)
+
+// instanceOrderAttributes are the attributes sent to the Gitops API, used
+// to attach its validation errors to the configuration.
+var instanceOrderAttributes = []string{
+	"instance_name",
+	"orderer_id",
+	"bits_account",
+	"service_id",
+	"replica_count",
+	"version",
+	"some_value",
+}
+
+// mutableInstanceAttributes are the instance attributes the Gitops API may
+// be configured to not update in place, see immutable_instance_attributes.
+var mutableInstanceAttributes = []string{
+	"bits_account",
+	"service_id",
+}
+
 // gitopsInstanceResource is the resource implementation.
 type gitopsInstanceResource struct {
-	client *gitopsclient.GitopsClient
+	client *gitopsApiClient
 }
 
 // gitopsInstanceResourceModel maps the resource schema data.
 type gitopsInstanceResourceModel struct {
-	Instance_id   types.String `tfsdk:"instance_id"`
-	Order_time    types.String `tfsdk:"order_time"`
-	Stage         types.String `tfsdk:"stage"`
-	Instance_name types.String `tfsdk:"instance_name"`
-	Orderer_id    types.String `tfsdk:"orderer_id"`
-	Bits_account  types.Int64  `tfsdk:"bits_account"`
-	Service_id    types.Int64  `tfsdk:"service_id"`
-	Replica_count types.Int64  `tfsdk:"replica_count"`
-	Version       types.String `tfsdk:"version"`
-	Some_value    types.String `tfsdk:"some_value"`
-	LastUpdated   types.String `tfsdk:"last_updated"`
+	Instance_id     types.String           `tfsdk:"instance_id"`
+	Order_time      types.String           `tfsdk:"order_time"`
+	Stage           types.String           `tfsdk:"stage"`
+	Instance_name   types.String           `tfsdk:"instance_name"`
+	Orderer_id      types.String           `tfsdk:"orderer_id"`
+	Bits_account    types.Int64            `tfsdk:"bits_account"`
+	Service_id      types.Int64            `tfsdk:"service_id"`
+	Replica_count   types.Int64            `tfsdk:"replica_count"`
+	Version         versionConstraintValue `tfsdk:"version"`
+	ResolvedVersion types.String           `tfsdk:"resolved_version"`
+	Some_value      types.String           `tfsdk:"some_value"`
+	Labels          types.Map              `tfsdk:"labels"`
+	LabelsAll       types.Map              `tfsdk:"labels_all"`
+	LastUpdated     types.String           `tfsdk:"last_updated"`
+	TargetStages    types.Set              `tfsdk:"target_stages"`
+	FailureStages   types.Set              `tfsdk:"failure_stages"`
+	Timeouts        timeouts.Value         `tfsdk:"timeouts"`
 }
 
 // Configure adds the provider configured client to the resource.
@@ -54,12 +95,12 @@ func (r *gitopsInstanceResource) Configure(_ context.Context, req resource.Confi
 		return
 	}
 
-	client, ok := req.ProviderData.(*gitopsclient.GitopsClient)
+	client, ok := req.ProviderData.(*gitopsApiClient)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *GitopsClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *gitopsApiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
@@ -74,7 +115,7 @@ func (r *gitopsInstanceResource) Metadata(_ context.Context, req resource.Metada
 }
 
 // Schema defines the schema for the resource.
-func (r *gitopsInstanceResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *gitopsInstanceResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Manages a Gitops instance",
 		Attributes: map[string]schema.Attribute{
@@ -86,7 +127,7 @@ func (r *gitopsInstanceResource) Schema(_ context.Context, _ resource.SchemaRequ
 				},
 			},
 			"order_time": schema.StringAttribute{
-				Description: "Name of the Gitops resource orderer",
+				Description: "Time the Gitops resource was ordered",
 				Computed:    true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
@@ -95,53 +136,170 @@ func (r *gitopsInstanceResource) Schema(_ context.Context, _ resource.SchemaRequ
 			"stage": schema.StringAttribute{
 				Description: "Stage",
 				Computed:    true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.UseStateForUnknown(),
-				},
 			},
 			"last_updated": schema.StringAttribute{
 				Description: "Timestamp of last update",
 				Computed:    true,
 			},
 			"instance_name": schema.StringAttribute{
-				Description: "Name of the Gitops resource instance",
-				Computed:    false,
-				Required:    true,
+				Description: fmt.Sprintf("Name of the Gitops resource instance. Up to %d letters, digits, spaces, "+
+					"dots, dashes and underscores, starting with a letter or digit.", maxInstanceNameLength),
+				Computed: false,
+				Required: true,
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, maxInstanceNameLength),
+					stringvalidator.RegexMatches(instanceNameRegexp,
+						"must start with a letter or digit and only contain letters, digits, spaces, dots, dashes and underscores"),
+				},
 			},
 			"orderer_id": schema.StringAttribute{
-				Description: "ID of the Gitops resource orderer",
-				Computed:    false,
-				Required:    true,
+				Description: "ID of the Gitops resource orderer, the e-mail address of the ordering person. " +
+					"Instances cannot change their orderer, changing it replaces the instance.",
+				Computed: false,
+				Required: true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(emailRegexp, "must be an e-mail address"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"bits_account": schema.Int64Attribute{
-				Description: "Account-ID of the Gitops resource instance",
-				Computed:    false,
-				Required:    true,
+				Description: "Account-ID of the Gitops resource instance. " +
+					"Changing it replaces the instance if listed in the provider immutable_instance_attributes.",
+				Computed: false,
+				Required: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
 			},
 			"service_id": schema.Int64Attribute{
-				Description: "Service-ID of the Gitops resource instance",
-				Computed:    false,
-				Required:    true,
+				Description: "Service-ID of the Gitops resource instance, checked against the Gitops API catalog while planning. " +
+					"Changing it replaces the instance if listed in the provider immutable_instance_attributes.",
+				Computed: false,
+				Required: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
 			},
 			"replica_count": schema.Int64Attribute{
-				Description: "Replica count of the Gitops resource instance",
-				Computed:    false,
-				Required:    true,
+				Description: fmt.Sprintf("Replica count of the Gitops resource instance, between %d and %d "+
+					"and within the replica range of the service in the catalog", minReplicaCount, maxReplicaCount),
+				Computed: false,
+				Required: true,
+				Validators: []validator.Int64{
+					int64validator.Between(minReplicaCount, maxReplicaCount),
+				},
 			},
 			"version": schema.StringAttribute{
-				Description: "Version of the Gitops resource instance",
-				Computed:    false,
-				Required:    true,
+				Description: "Version of the Gitops resource instance, either a semantic version like \"3.2.1\" or a wildcard version like \"3.2.*\" " +
+					"satisfied by one of the versions of the service in the catalog. The constraint is kept as configured, " +
+					"the deployed version is reported as resolved_version.",
+				CustomType: versionConstraintType{},
+				Computed:   false,
+				Required:   true,
+				Validators: []validator.String{
+					versionConstraintValidator{},
+				},
+			},
+			"resolved_version": schema.StringAttribute{
+				Description: "Concrete version of the Gitops resource instance satisfying version: the version reported by the Gitops API, " +
+					"or the highest version of the service in the catalog satisfying a wildcard version. " +
+					"A newer version in the catalog satisfying version is planned as an update rolling it out.",
+				Computed: true,
 			},
 			"some_value": schema.StringAttribute{
 				Description: "Some custom value of the Gitops resource instance",
 				Computed:    false,
 				Required:    true,
 			},
+			"labels": schema.MapAttribute{
+				Description: "Labels of the Gitops resource instance, e.g. its team or cost center, sent to the Gitops API as instance metadata. " +
+					"Labels override the provider default_labels with the same key.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"labels_all": schema.MapAttribute{
+				Description: "Labels of the Gitops resource instance including the provider default_labels",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"target_stages": schema.SetAttribute{
+				Description: "Stages the instance has to reach before create and update complete. " +
+					"Set to an empty set to skip waiting. Defaults to [\"deployed\"].",
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     setdefault.StaticValue(stringSetValue(defaultTargetStages)),
+			},
+			"failure_stages": schema.SetAttribute{
+				Description: "Stages that fail create and update while waiting for target_stages. Defaults to [\"failed\"].",
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     setdefault.StaticValue(stringSetValue(defaultFailureStages)),
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
 		},
 	}
 }
 
+// ModifyPlan plans labels_all, checks the instance against the constraints
+// of its catalog service and replaces instances when attributes change
+// that the Gitops API is configured to not update in place. The catalog
+// is checked here rather than in ValidateConfig, which Terraform may call
+// before the provider is configured.
+func (r *gitopsInstanceResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to plan on destroy
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var labels types.Map
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("labels"), &labels)...)
+	configured, known, diags := configuredLabels(ctx, labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Unknown labels leave labels_all unknown until apply
+	if known {
+		labelsAll := labelsValue(mergeLabels(r.client.defaultLabels, configured))
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("labels_all"), labelsAll)...)
+	}
+
+	resp.Diagnostics.Append(r.checkCatalogConstraints(ctx, req)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Nothing to replace on create
+	if req.State.Raw.IsNull() {
+		return
+	}
+
+	r.planResolvedVersion(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, attribute := range r.client.immutableInstanceAttributes {
+		var planned, current types.Int64
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root(attribute), &planned)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root(attribute), &current)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if !planned.Equal(current) {
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root(attribute))
+		}
+	}
+}
+
 // Create a new resource.
 func (r *gitopsInstanceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	// Retrieve values from plan
@@ -152,8 +310,18 @@ func (r *gitopsInstanceResource) Create(ctx context.Context, req resource.Create
 		return
 	}
 
+	labels, _, diags := configuredLabels(ctx, plan.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	labelsAll := mergeLabels(r.client.defaultLabels, labels)
+
 	// Generate API request body from plan
-	var instance_order gitopsclient.InstanceOrder
+	var instance_order instanceOrder
+	if len(labelsAll) > 0 {
+		instance_order.Metadata = &instanceMetadata{Labels: labelsAll}
+	}
 	instance_order.Instance_name = plan.Instance_name.ValueString()
 	instance_order.Orderer_id = plan.Orderer_id.ValueString()
 	instance_order.Bits_account = uint64(plan.Bits_account.ValueInt64())
@@ -162,20 +330,75 @@ func (r *gitopsInstanceResource) Create(ctx context.Context, req resource.Create
 	instance_order.Version = plan.Version.ValueString()
 	instance_order.Some_value = plan.Some_value.ValueString()
 
-	// Create new gitopsInstance
-	gitopsInstance, err := r.client.PostInstanceOrder(instance_order)
+	// The order token is sent as idempotency key, so the Gitops API
+	// recognizes retried requests of this order, also by a later apply if
+	// the outcome of this one stays unknown.
+	orderToken, orderedAt, err := r.client.orderToken("instance", instance_order)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error creating instance gitopsInstance",
-			"Could not create gitopsInstance, unexpected error: "+err.Error(),
+			"Could not generate order token, unexpected error: "+err.Error(),
+		)
+		return
+	}
+
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	lookupCtx := ctx
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	// Create new gitopsInstance
+	gitopsInstance, err := r.client.PostInstanceOrder(ctx, instance_order, orderToken)
+	if err != nil && orderMayHaveSucceeded(err) {
+		// The order may have been placed even though its response got
+		// lost, adopt the instance instead of ordering it again next time.
+		matches := func(instance gitopsApiInstance) bool {
+			return instance.Instance_name == instance_order.Instance_name &&
+				instance.Orderer_id == instance_order.Orderer_id &&
+				instance.Bits_account == instance_order.Bits_account &&
+				instance.Service_id == instance_order.Service_id
+		}
+		if instance, found := adoptLostOrder(lookupCtx, "instance", err, orderedAt, r.client.ListInstances, matches); found {
+			gitopsInstance, err = instance, nil
+		}
+	}
+	if err == nil || !orderMayHaveSucceeded(err) {
+		// The order was placed or rejected, it must not be repeated
+		if settleErr := r.client.orderSettled("instance", instance_order); settleErr != nil {
+			tflog.Warn(ctx, "Could not remove order token", map[string]any{
+				"error": settleErr.Error(),
+			})
+		}
+	}
+	if err != nil {
+		addApiErrorDiagnostics(&resp.Diagnostics,
+			"Error creating instance gitopsInstance",
+			"Could not create gitopsInstance",
+			err, instanceOrderAttributes...,
 		)
 		return
 	}
 
+	// Wait for the GitOps pipeline to roll out the instance. The instance
+	// exists at this point, so it is saved to state even if waiting fails.
+	gitopsInstance, err = r.waitForStage(ctx, plan, gitopsInstance)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error waiting for gitopsInstance",
+			"Instance "+gitopsInstance.Instance_id+" was created but did not reach its target stage: "+err.Error(),
+		)
+	}
+
 	// Map response body to schema and populate Computed attribute values
 	plan.Instance_id = types.StringValue(gitopsInstance.Instance_id)
 	plan.Order_time = types.StringValue(gitopsInstance.Order_time)
 	plan.Stage = types.StringValue(gitopsInstance.Stage)
+	plan.ResolvedVersion = r.client.resolvedVersion(ctx, gitopsInstance, types.StringNull())
+	plan.LabelsAll = labelsValue(labelsAll)
 	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
 
 	// Set state to fully populated data
@@ -197,11 +420,21 @@ func (r *gitopsInstanceResource) Read(ctx context.Context, req resource.ReadRequ
 	}
 
 	// Get refreshed instance value from Gitops API
-	gitopsInstance, err := r.client.GetInstance(state.Instance_id.ValueString())
+	gitopsInstance, err := r.client.GetInstance(ctx, state.Instance_id.ValueString())
+	if isNotFound(err) {
+		// The instance was deleted outside of Terraform, drop it from
+		// state so Terraform plans to re-create it.
+		tflog.Warn(ctx, "gitops instance not found, removing it from state", map[string]any{
+			"instance_id": state.Instance_id.ValueString(),
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
-		resp.Diagnostics.AddError(
+		addApiErrorDiagnostics(&resp.Diagnostics,
 			"Error Reading gitopsInstance",
-			"Could not read gitopsInstance ID "+state.Instance_id.ValueString()+": "+err.Error(),
+			"Could not read gitopsInstance ID "+state.Instance_id.ValueString(),
+			err,
 		)
 		return
 	}
@@ -214,8 +447,20 @@ func (r *gitopsInstanceResource) Read(ctx context.Context, req resource.ReadRequ
 	state.Service_id = types.Int64Value(int64(gitopsInstance.Service_id))
 	state.Stage = types.StringValue(gitopsInstance.Stage)
 	state.Replica_count = types.Int64Value(int64(gitopsInstance.Replica_count))
-	state.Version = types.StringValue(gitopsInstance.Version)
+	state.Version = instanceVersion(state.Version, gitopsInstance.Version)
+	state.ResolvedVersion = r.client.resolvedVersion(ctx, gitopsInstance, state.ResolvedVersion)
 	state.Some_value = types.StringValue(gitopsInstance.Some_value)
+	state.LabelsAll = labelsValue(gitopsInstance.Metadata.Labels)
+	state.Labels, diags = instanceLabels(ctx, gitopsInstance.Metadata.Labels, r.client.defaultLabels, state.Labels)
+	resp.Diagnostics.Append(diags...)
+
+	// Imported instances have no waiter configuration yet
+	if state.TargetStages.IsNull() {
+		state.TargetStages = stringSetValue(defaultTargetStages)
+	}
+	if state.FailureStages.IsNull() {
+		state.FailureStages = stringSetValue(defaultFailureStages)
+	}
 
 	// Set refreshed state
 	diags = resp.State.Set(ctx, &state)
@@ -227,37 +472,93 @@ func (r *gitopsInstanceResource) Read(ctx context.Context, req resource.ReadRequ
 
 func (r *gitopsInstanceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	// Retrieve values from plan
-	var plan gitopsInstanceResourceModel
+	var plan, state gitopsInstanceResourceModel
 	diags := req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
+	diags = req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	// The catalog version to roll out, if any, is only needed by this
+	// update and not kept in state
+	var latest string
+	planned, diags := req.Private.GetKey(ctx, plannedVersionKey)
+	resp.Diagnostics.Append(diags...)
+	if len(planned) > 0 {
+		if err := json.Unmarshal(planned, &latest); err != nil {
+			resp.Diagnostics.AddError("Unable to Update gitopsInstance", "Could not decode planned version, unexpected error: "+err.Error())
+		}
+	}
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, plannedVersionKey, nil)...)
+
+	labels, _, diags := configuredLabels(ctx, plan.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	labelsAll := mergeLabels(r.client.defaultLabels, labels)
+
 	// Generate API request body from plan
-	var instanceUpdate gitopsclient.InstanceUpdate
-	instanceUpdate.Instance_name = plan.Instance_name.ValueString()
-	instanceUpdate.Bits_account = uint64(plan.Bits_account.ValueInt64())
-	instanceUpdate.Service_id = uint64(plan.Service_id.ValueInt64())
-	instanceUpdate.Replica_count = uint64(plan.Replica_count.ValueInt64())
-	instanceUpdate.Version = plan.Version.ValueString()
-	instanceUpdate.Some_value = plan.Some_value.ValueString()
+	var instance_update instanceUpdate
+	instance_update.Metadata.Labels = labelsAll
+	instance_update.Instance_name = plan.Instance_name.ValueString()
+	instance_update.Bits_account = uint64(plan.Bits_account.ValueInt64())
+	instance_update.Service_id = uint64(plan.Service_id.ValueInt64())
+	instance_update.Replica_count = uint64(plan.Replica_count.ValueInt64())
+	instance_update.Version = plan.Version.ValueString()
+	instance_update.Some_value = plan.Some_value.ValueString()
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
 
 	// Update existing gitopsInstance
-	gitopsInstance, err := r.client.PutInstance(plan.Instance_id.ValueString(), instanceUpdate)
+	gitopsInstance, err := r.client.PutInstance(ctx, plan.Instance_id.ValueString(), instance_update)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		addApiErrorDiagnostics(&resp.Diagnostics,
 			"Error Updating Gitpos Instance",
-			"Could not update instance, unexpected error: "+err.Error(),
+			"Could not update instance",
+			err, instanceOrderAttributes...,
 		)
 		return
 	}
+
+	gitopsInstance, err = r.waitForStage(ctx, plan, gitopsInstance)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error waiting for gitopsInstance",
+			"Instance "+gitopsInstance.Instance_id+" was updated but did not reach its target stage: "+err.Error(),
+		)
+	}
 	plan.Bits_account = types.Int64Value(int64(gitopsInstance.Bits_account))
 	plan.Service_id = types.Int64Value(int64(gitopsInstance.Service_id))
 	plan.Instance_name = types.StringValue(gitopsInstance.Instance_name)
 	plan.Replica_count = types.Int64Value(int64(gitopsInstance.Replica_count))
-	plan.Version = types.StringValue(gitopsInstance.Version)
+	// The planned version constraint is kept, see instanceVersion
+	resolvedVersion := r.client.resolvedVersion(ctx, gitopsInstance, plan.ResolvedVersion)
+	if plan.ResolvedVersion.IsUnknown() {
+		plan.ResolvedVersion = resolvedVersion
+		resp.Diagnostics.Append(checkVersionRolledOut(plan, state, latest)...)
+	} else if plan.ResolvedVersion.Equal(resolvedVersion) {
+		plan.ResolvedVersion = resolvedVersion
+	} else {
+		// Terraform rejects applied values differing from the planned
+		// ones, the next refresh picks the new version up
+		tflog.Warn(ctx, "gitops instance version changed while applying", map[string]any{
+			"instance_id":      plan.Instance_id.ValueString(),
+			"planned_version":  plan.ResolvedVersion.ValueString(),
+			"resolved_version": resolvedVersion.ValueString(),
+		})
+	}
 	plan.Some_value = types.StringValue(gitopsInstance.Some_value)
+	plan.Stage = types.StringValue(gitopsInstance.Stage)
+	plan.LabelsAll = labelsValue(labelsAll)
 	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
 
 	diags = resp.State.Set(ctx, plan)
@@ -276,12 +577,25 @@ func (r *gitopsInstanceResource) Delete(ctx context.Context, req resource.Delete
 		return
 	}
 
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
 	// Delete existing instance
-	err := r.client.DeleteInstance(state.Instance_id.ValueString())
+	err := r.client.DeleteInstance(ctx, state.Instance_id.ValueString())
+	if isNotFound(err) {
+		// Already gone, nothing left to delete
+		return
+	}
 	if err != nil {
-		resp.Diagnostics.AddError(
+		addApiErrorDiagnostics(&resp.Diagnostics,
 			"Error Deleting gitopsInstance"+state.Instance_id.ValueString(),
-			"Could not delete gitopsInstance, unexpected error: "+err.Error(),
+			"Could not delete gitopsInstance",
+			err,
 		)
 		return
 	}
@@ -291,3 +605,101 @@ func (r *gitopsInstanceResource) ImportState(ctx context.Context, req resource.I
 	// Retrieve import ID and save to id attribute
 	resource.ImportStatePassthroughID(ctx, path.Root("instance_id"), req, resp)
 }
+
+// waitForStage waits for the instance to reach one of the planned target stages.
+func (r *gitopsInstanceResource) waitForStage(ctx context.Context, plan gitopsInstanceResourceModel, instance gitopsApiInstance) (gitopsApiInstance, error) {
+	var targetStages, failureStages []string
+	plan.TargetStages.ElementsAs(ctx, &targetStages, false)
+	plan.FailureStages.ElementsAs(ctx, &failureStages, false)
+	return waitForInstanceStage(ctx, r.client, instance, targetStages, failureStages)
+}
+
+// instanceVersion returns the version constraint to keep in state given the
+// version reported by the Gitops API. The configured constraint is kept if
+// the reported version satisfies it, otherwise the reported version is
+// adopted, so that Terraform plans to restore the constraint unless both
+// are semantically equal.
+func instanceVersion(current versionConstraintValue, reported string) versionConstraintValue {
+	if current.IsNull() || current.IsUnknown() {
+		return newVersionConstraintValue(reported)
+	}
+	constraint, err := parseVersionConstraint(current.ValueString())
+	if err != nil {
+		return newVersionConstraintValue(reported)
+	}
+	if version, err := parseVersion(reported); err == nil && constraint.Check(version) {
+		return current
+	}
+	return newVersionConstraintValue(reported)
+}
+
+// checkVersionRolledOut reports an error if an update planned to roll out
+// the catalog version latest, see planResolvedVersion, left the instance
+// on its previous version. The update would be planned again on every plan
+// otherwise.
+func checkVersionRolledOut(applied gitopsInstanceResourceModel, state gitopsInstanceResourceModel, latest string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if latest == "" || state.ResolvedVersion.IsNull() || applied.ResolvedVersion.IsNull() ||
+		!equalVersions(applied.ResolvedVersion.ValueString(), state.ResolvedVersion.ValueString()) {
+		return diags
+	}
+	diags.AddAttributeError(
+		path.Root("version"),
+		"Version Not Rolled Out",
+		fmt.Sprintf("Instance %s still runs version %s after the update, while the catalog offers version %s satisfying %q. "+
+			"Set version to %q to upgrade the instance.",
+			applied.Instance_id.ValueString(), state.ResolvedVersion.ValueString(), latest, applied.Version.ValueString(), latest),
+	)
+	return diags
+}
+
+// plannedVersionKey is the private state key of the catalog version an
+// update is planned to roll out, see planResolvedVersion.
+const plannedVersionKey = "planned_version"
+
+// planResolvedVersion keeps resolved_version while the version constraint
+// and the service are unchanged, unless the catalog offers another version
+// satisfying the constraint. That version is rolled out by an update, so
+// resolved_version is planned to be known after apply. The version is kept
+// in private state, Update fails if the Gitops API does not roll it out,
+// see checkVersionRolledOut.
+func (r *gitopsInstanceResource) planResolvedVersion(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, plannedVersionKey, nil)...)
+	var plan, state gitopsInstanceResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() || plan.Version.IsUnknown() || !plan.Service_id.Equal(state.Service_id) {
+		return
+	}
+	equal, diags := state.Version.StringSemanticEquals(ctx, plan.Version)
+	resp.Diagnostics.Append(diags...)
+	if !equal {
+		return
+	}
+
+	latest, ok, err := r.client.catalogVersion(ctx, uint64(plan.Service_id.ValueInt64()), plan.Version.ValueString())
+	if err != nil || !ok || equalVersions(latest, state.ResolvedVersion.ValueString()) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("resolved_version"), state.ResolvedVersion)...)
+		return
+	}
+	planned, err := json.Marshal(latest)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to Plan gitopsInstance Version", "Could not encode planned version, unexpected error: "+err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, plannedVersionKey, planned)...)
+	// Without changes of the configuration Terraform keeps the other
+	// computed attributes, which the update changes as well
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("resolved_version"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("stage"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("last_updated"), types.StringUnknown())...)
+}
+
+// stringSetValue converts a string slice into a known set value.
+func stringSetValue(values []string) types.Set {
+	elements := make([]attr.Value, 0, len(values))
+	for _, value := range values {
+		elements = append(elements, types.StringValue(value))
+	}
+	return types.SetValueMust(types.StringType, elements)
+}
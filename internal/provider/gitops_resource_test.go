@@ -0,0 +1,639 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+// testAccInstanceConfig returns a gitops_instance named name with
+// replicaCount replicas.
+func testAccInstanceConfig(name string, replicaCount int) string {
+	return fmt.Sprintf(`
+resource "gitops_instance" "test" {
+  instance_name = %[1]q
+  orderer_id    = "jane.doe@example.com"
+  bits_account  = 4711
+  service_id    = 42
+  replica_count = %[2]d
+  version       = "3.2.1"
+  some_value    = "some value"
+}
+`, name, replicaCount)
+}
+
+// testAccCaptureInstanceId stores the instance_id of resourceName in id.
+func testAccCaptureInstanceId(resourceName string, id *string) resource.TestCheckFunc {
+	return resource.TestCheckResourceAttrWith(resourceName, "instance_id", func(value string) error {
+		*id = value
+		return nil
+	})
+}
+
+// testAccCheckInstanceDestroyed checks that no instance is left in api.
+func testAccCheckInstanceDestroyed(api *fakeGitopsApi) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		if count := api.instanceCount(); count != 0 {
+			return fmt.Errorf("got %d instances left, want all destroyed", count)
+		}
+		return nil
+	}
+}
+
+func TestAccGitopsInstanceResource(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir())
+	var instanceId string
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: config + testAccInstanceConfig("test-instance", 1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("gitops_instance.test", "instance_id"),
+					resource.TestCheckResourceAttrSet("gitops_instance.test", "order_time"),
+					resource.TestCheckResourceAttrSet("gitops_instance.test", "last_updated"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "stage", "deployed"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "instance_name", "test-instance"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "orderer_id", "jane.doe@example.com"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "bits_account", "4711"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "service_id", "42"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "replica_count", "1"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "version", "3.2.1"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "some_value", "some value"),
+					resource.TestCheckTypeSetElemAttr("gitops_instance.test", "target_stages.*", "deployed"),
+					resource.TestCheckTypeSetElemAttr("gitops_instance.test", "failure_stages.*", "failed"),
+					testAccCaptureInstanceId("gitops_instance.test", &instanceId),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:                         "gitops_instance.test",
+				ImportState:                          true,
+				ImportStateVerify:                    true,
+				ImportStateIdFunc:                    func(*terraform.State) (string, error) { return instanceId, nil },
+				ImportStateVerifyIdentifierAttribute: "instance_id",
+				// The time of the last update is only known to the
+				// Terraform run that did it.
+				ImportStateVerifyIgnore: []string{"last_updated"},
+			},
+			// Update and Read testing
+			{
+				Config: config + testAccInstanceConfig("test-instance", 3),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPtr("gitops_instance.test", "instance_id", &instanceId),
+					resource.TestCheckResourceAttr("gitops_instance.test", "replica_count", "3"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "stage", "deployed"),
+				),
+			},
+			// Drift of an attribute changed outside of Terraform
+			{
+				PreConfig: func() {
+					api.modifyInstance(instanceId, func(instance *gitopsApiInstance) {
+						instance.Replica_count = 7
+					})
+				},
+				Config: config + testAccInstanceConfig("test-instance", 3),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPtr("gitops_instance.test", "instance_id", &instanceId),
+					resource.TestCheckResourceAttr("gitops_instance.test", "replica_count", "3"),
+				),
+			},
+			// Drift of an instance deleted outside of Terraform
+			{
+				PreConfig: func() {
+					api.removeInstance(instanceId)
+				},
+				Config: config + testAccInstanceConfig("test-instance", 3),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionCreate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrWith("gitops_instance.test", "instance_id", func(value string) error {
+						if value == instanceId {
+							return fmt.Errorf("got instance_id %s of the deleted instance", value)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr("gitops_instance.test", "stage", "deployed"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceReplacesOnOrdererChange(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir())
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config: config + testAccInstanceConfig("test-instance", 1),
+			},
+			{
+				Config: config + strings.ReplaceAll(testAccInstanceConfig("test-instance", 1), "jane.doe", "john.doe"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+				Check: resource.TestCheckResourceAttr("gitops_instance.test", "orderer_id", "john.doe@example.com"),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceImmutableAttributes(t *testing.T) {
+	for name, test := range map[string]struct {
+		attributes string
+		action     plancheck.ResourceActionType
+		replaced   bool
+	}{
+		"immutable": {
+			attributes: `immutable_instance_attributes = ["bits_account"]`,
+			action:     plancheck.ResourceActionReplace,
+			replaced:   true,
+		},
+		"mutable": {
+			action: plancheck.ResourceActionUpdate,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := newFakeGitopsApi(t)
+			config := testAccProviderConfigWith(api, t.TempDir(), test.attributes)
+			var firstId, secondId string
+
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				CheckDestroy:             testAccCheckInstanceDestroyed(api),
+				Steps: []resource.TestStep{
+					{
+						Config: config + testAccInstanceConfig("test-instance", 1),
+						Check:  testAccCaptureInstanceId("gitops_instance.test", &firstId),
+					},
+					{
+						Config: config + strings.ReplaceAll(testAccInstanceConfig("test-instance", 1), "4711", "4712"),
+						ConfigPlanChecks: resource.ConfigPlanChecks{
+							PreApply: []plancheck.PlanCheck{
+								plancheck.ExpectResourceAction("gitops_instance.test", test.action),
+							},
+						},
+						Check: resource.ComposeAggregateTestCheckFunc(
+							resource.TestCheckResourceAttr("gitops_instance.test", "bits_account", "4712"),
+							testAccCaptureInstanceId("gitops_instance.test", &secondId),
+							func(_ *terraform.State) error {
+								if replaced := firstId != secondId; replaced != test.replaced {
+									return fmt.Errorf("got instance %s after %s, want replaced %t", secondId, firstId, test.replaced)
+								}
+								return nil
+							},
+						),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAccGitopsInstanceResourceApiValidationError(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.failNext(http.MethodPost, "/instances", http.StatusUnprocessableEntity, map[string]any{
+		"detail": []map[string]any{{
+			"loc":  []string{"body", "replica_count"},
+			"msg":  "replica count exceeds the quota of the account",
+			"type": "value_error",
+		}},
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccProviderConfig(api, t.TempDir()) + testAccInstanceConfig("test-instance", 1),
+				ExpectError: regexp.MustCompile(`replica count exceeds the quota of the\s+account`),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceRetriesLostOrder(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.dropNext(http.MethodPost, "/instances")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, t.TempDir()) + testAccInstanceConfig("test-instance", 1),
+				Check: func(_ *terraform.State) error {
+					if count := api.instanceCount(); count != 1 {
+						return fmt.Errorf("got %d instances, want the retried order to not order twice", count)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceAdoptsLostOrder(t *testing.T) {
+	for name, failOrder := range map[string]func(api *fakeGitopsApi){
+		"server error": func(api *fakeGitopsApi) {
+			// The order is placed, but it and both of its retries fail
+			api.failNextAfterProcessing(http.MethodPost, "/instances", http.StatusInternalServerError, map[string]any{"detail": "internal error"})
+			for range 2 {
+				api.failNext(http.MethodPost, "/instances", http.StatusInternalServerError, map[string]any{"detail": "internal error"})
+			}
+		},
+		"dropped connection": func(api *fakeGitopsApi) {
+			// Lose the responses of the order and both of its retries
+			for range 3 {
+				api.dropNext(http.MethodPost, "/instances")
+			}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := newFakeGitopsApi(t)
+			failOrder(api)
+
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				CheckDestroy:             testAccCheckInstanceDestroyed(api),
+				Steps: []resource.TestStep{
+					{
+						Config: testAccProviderConfig(api, t.TempDir()) + testAccInstanceConfig("test-instance", 1),
+						Check: resource.ComposeAggregateTestCheckFunc(
+							resource.TestCheckResourceAttr("gitops_instance.test", "instance_id", "inst-1"),
+							resource.TestCheckResourceAttr("gitops_instance.test", "stage", "deployed"),
+							func(_ *terraform.State) error {
+								if count := api.instanceCount(); count != 1 {
+									return fmt.Errorf("got %d instances, want the lost order adopted", count)
+								}
+								return nil
+							},
+						),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAccGitopsInstanceResourceReusesTokenOfLostOrder(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	// Every attempt of the order and of looking up the ordered instance
+	// fails, so the outcome of the first apply stays unknown
+	for range 3 {
+		api.dropNext(http.MethodPost, "/instances")
+		api.failNext(http.MethodGet, "/instances", http.StatusServiceUnavailable, map[string]any{"detail": "unavailable"})
+	}
+	config := testAccProviderConfig(api, t.TempDir()) + testAccInstanceConfig("test-instance", 1)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config:      config,
+				ExpectError: regexp.MustCompile(`Could not create gitopsInstance`),
+			},
+			{
+				Config: config,
+				Check: func(_ *terraform.State) error {
+					if count := api.instanceCount(); count != 1 {
+						return fmt.Errorf("got %d instances, want the next apply to not order twice", count)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceAdoptsOrderOfEarlierApply(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	cachePath := t.TempDir()
+	for range 3 {
+		api.dropNext(http.MethodPost, "/instances")
+		api.failNext(http.MethodGet, "/instances", http.StatusServiceUnavailable, map[string]any{"detail": "unavailable"})
+	}
+	config := testAccProviderConfig(api, cachePath) + testAccInstanceConfig("test-instance", 1)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config:      config,
+				ExpectError: regexp.MustCompile(`Could not create gitopsInstance`),
+			},
+			{
+				// The first apply was a while ago and the Gitops API fails
+				// the repeated order, the instance is looked up instead
+				PreConfig: func() {
+					earlier := time.Now().Add(-10 * time.Minute)
+					api.modifyInstance("inst-1", func(instance *gitopsApiInstance) {
+						instance.Order_time = earlier.UTC().Format(time.RFC3339)
+					})
+					tokens, err := filepath.Glob(filepath.Join(cachePath, "orders", "*"))
+					if err != nil || len(tokens) != 1 {
+						t.Fatalf("got order tokens %v, %v, want one", tokens, err)
+					}
+					if err := os.Chtimes(tokens[0], earlier, earlier); err != nil {
+						t.Fatal(err)
+					}
+					for range 3 {
+						api.failNext(http.MethodPost, "/instances", http.StatusInternalServerError, map[string]any{"detail": "internal error"})
+					}
+				},
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "instance_id", "inst-1"),
+					func(_ *terraform.State) error {
+						if count := api.instanceCount(); count != 1 {
+							return fmt.Errorf("got %d instances, want the instance of the first apply to be adopted", count)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceFailureStage(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.rolloutStage = "failed"
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccProviderConfig(api, t.TempDir()) + testAccInstanceConfig("test-instance", 1),
+				ExpectError: regexp.MustCompile(`reached failure stage "failed"`),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceLabels(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfigWith(api, t.TempDir(), `default_labels = {
+    team        = "platform"
+    cost-center = "1234"
+  }`)
+	labelledInstanceConfig := func(labels string) string {
+		return strings.Replace(testAccInstanceConfig("test-instance", 1), "some_value", labels+"\n  some_value", 1)
+	}
+	var instanceId string
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config: config + labelledInstanceConfig(`labels = { team = "payments", app = "redis" }`),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectKnownValue("gitops_instance.test", tfjsonpath.New("labels_all"), knownvalue.MapExact(map[string]knownvalue.Check{
+							"team":        knownvalue.StringExact("payments"),
+							"cost-center": knownvalue.StringExact("1234"),
+							"app":         knownvalue.StringExact("redis"),
+						})),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "labels.%", "2"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "labels_all.%", "3"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "labels_all.cost-center", "1234"),
+					testAccCaptureInstanceId("gitops_instance.test", &instanceId),
+					func(_ *terraform.State) error {
+						instance, _ := api.instance(instanceId)
+						if instance.Metadata.Labels["team"] != "payments" || instance.Metadata.Labels["cost-center"] != "1234" {
+							return fmt.Errorf("got instance labels %v, want the merged labels", instance.Metadata.Labels)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				ResourceName:                         "gitops_instance.test",
+				ImportState:                          true,
+				ImportStateVerify:                    true,
+				ImportStateIdFunc:                    func(*terraform.State) (string, error) { return instanceId, nil },
+				ImportStateVerifyIdentifierAttribute: "instance_id",
+				ImportStateVerifyIgnore:              []string{"last_updated"},
+			},
+			// Only default labels
+			{
+				Config: config + testAccInstanceConfig("test-instance", 1),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("gitops_instance.test", "labels.%"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "labels_all.%", "2"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "labels_all.team", "platform"),
+				),
+			},
+			// Label added outside of Terraform
+			{
+				PreConfig: func() {
+					api.modifyInstance(instanceId, func(instance *gitopsApiInstance) {
+						instance.Metadata.Labels["extra"] = "drift"
+					})
+				},
+				Config: config + testAccInstanceConfig("test-instance", 1),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.TestCheckNoResourceAttr("gitops_instance.test", "labels_all.extra"),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceResolvedVersion(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir())
+	wildcardConfig := config + strings.Replace(testAccInstanceConfig("test-instance", 1), `"3.2.1"`, `"3.2.*"`, 1)
+	var instanceId string
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			// The wildcard is resolved with the catalog
+			{
+				Config: wildcardConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "version", "3.2.*"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "resolved_version", "3.2.1"),
+					testAccCaptureInstanceId("gitops_instance.test", &instanceId),
+				),
+			},
+			// A concrete version reported by the Gitops API keeps the
+			// constraint
+			{
+				PreConfig: func() {
+					api.modifyInstance(instanceId, func(instance *gitopsApiInstance) {
+						instance.Version = "3.2.1"
+					})
+				},
+				Config: wildcardConfig,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectEmptyPlan(),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "version", "3.2.*"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "resolved_version", "3.2.1"),
+				),
+			},
+			// A newer version in the catalog is rolled out by an update
+			{
+				PreConfig: func() {
+					api.setServiceVersions(42, "3.2.0", "3.2.1", "3.2.5", "3.3.0")
+					api.modifyInstance(instanceId, func(instance *gitopsApiInstance) {
+						instance.Version = "3.2.*"
+					})
+				},
+				Config: wildcardConfig,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionUpdate),
+						plancheck.ExpectUnknownValue("gitops_instance.test", tfjsonpath.New("resolved_version")),
+					},
+				},
+				Check: resource.TestCheckResourceAttr("gitops_instance.test", "resolved_version", "3.2.5"),
+			},
+			// A version not satisfying the constraint is drift
+			{
+				PreConfig: func() {
+					api.modifyInstance(instanceId, func(instance *gitopsApiInstance) {
+						instance.Version = "3.3.0"
+					})
+				},
+				Config: wildcardConfig,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "version", "3.2.*"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "resolved_version", "3.2.5"),
+					func(_ *terraform.State) error {
+						if instance, _ := api.instance(instanceId); instance.Version != "3.2.*" {
+							return fmt.Errorf("got instance version %q, want the constraint restored", instance.Version)
+						}
+						return nil
+					},
+				),
+			},
+			// A version normalized by the Gitops API is no diff
+			{
+				PreConfig: func() {
+					api.modifyInstance(instanceId, func(instance *gitopsApiInstance) {
+						instance.Version = "3.2.5"
+					})
+				},
+				Config: config + strings.Replace(testAccInstanceConfig("test-instance", 1), `"3.2.1"`, `"3.2.5"`, 1),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("gitops_instance.test", plancheck.ResourceActionUpdate),
+					},
+				},
+			},
+			{
+				PreConfig: func() {
+					api.modifyInstance(instanceId, func(instance *gitopsApiInstance) {
+						instance.Version = "3.2.5+build.7"
+					})
+				},
+				Config: config + strings.Replace(testAccInstanceConfig("test-instance", 1), `"3.2.1"`, `"3.2.5"`, 1),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectEmptyPlan(),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "version", "3.2.5"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "resolved_version", "3.2.5+build.7"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitopsInstanceResourceVersionNotRolledOut(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	wildcardConfig := testAccProviderConfig(api, t.TempDir()) +
+		strings.Replace(testAccInstanceConfig("test-instance", 1), `"3.2.1"`, `"3.2.*"`, 1)
+	var instanceId string
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config: wildcardConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "resolved_version", "3.2.1"),
+					testAccCaptureInstanceId("gitops_instance.test", &instanceId),
+				),
+			},
+			// The update planned for a newer version in the catalog fails
+			// instead of being planned again on every plan
+			{
+				PreConfig: func() {
+					api.modifyInstance(instanceId, func(instance *gitopsApiInstance) {
+						instance.Version = "3.2.1"
+					})
+					api.pinVersions()
+					api.setServiceVersions(42, "3.2.0", "3.2.1", "3.2.5")
+				},
+				Config:      wildcardConfig,
+				ExpectError: regexp.MustCompile(`(?s)Version Not Rolled Out.*still runs version\s+3.2.1\s+after\s+the\s+update,\s+while\s+the\s+catalog\s+offers\s+version\s+3.2.5`),
+			},
+		},
+	})
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Retry policy defaults, used when neither the provider configuration
+// nor the environment sets them.
+const (
+	defaultMaxRetries   = 3
+	defaultRetryMinWait = 1 * time.Second
+	defaultRetryMaxWait = 30 * time.Second
+)
+
+// gitopsRetryPolicy controls how often and how long transient Gitops API
+// failures are retried.
+type gitopsRetryPolicy struct {
+	MaxRetries int
+	MinWait    time.Duration
+	MaxWait    time.Duration
+}
+
+// defaultRetryPolicy returns the retry policy used without configuration.
+func defaultRetryPolicy() gitopsRetryPolicy {
+	return gitopsRetryPolicy{
+		MaxRetries: defaultMaxRetries,
+		MinWait:    defaultRetryMinWait,
+		MaxWait:    defaultRetryMaxWait,
+	}
+}
+
+// shouldRetry reports whether a request that failed with err or resp is
+// worth another attempt. Connection failures and overloaded or
+// temporarily unavailable servers are, context cancellation and all
+// other responses are not.
+func (p gitopsRetryPolicy) shouldRetry(ctx context.Context, resp *resty.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode() {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// wait returns how long to wait before retry number attempt (starting at
+// 0). The exponential backoff is jittered to spread retries of concurrent
+// operations, and a Retry-After header of resp takes precedence. Waits
+// never exceed MaxWait.
+func (p gitopsRetryPolicy) wait(attempt int, resp *resty.Response) time.Duration {
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header().Get("Retry-After"), time.Now()); ok {
+			return min(retryAfter, p.MaxWait)
+		}
+	}
+	backoff := p.MinWait
+	for i := 0; i < attempt && backoff < p.MaxWait; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, p.MaxWait)
+	// Full jitter within the upper half of the backoff
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or
+// as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource                     = &gitopsServiceDataSource{}
+	_ datasource.DataSourceWithConfigure        = &gitopsServiceDataSource{}
+	_ datasource.DataSourceWithConfigValidators = &gitopsServiceDataSource{}
+)
+
+// NewGitopsServiceDataSource is a helper function to simplify the provider implementation.
+func NewGitopsServiceDataSource() datasource.DataSource {
+	return &gitopsServiceDataSource{}
+}
+
+// gitopsServiceDataSource is the data source implementation.
+type gitopsServiceDataSource struct {
+	client *gitopsApiClient
+}
+
+// Metadata returns the data source type name.
+func (d *gitopsServiceDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_service"
+}
+
+// Schema defines the schema for the data source.
+func (d *gitopsServiceDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Looks up a service of the Gitops API catalog by id or by name",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.Int64Attribute{
+				Description: "Service-ID of the service, the service_id of its instances",
+				Optional:    true,
+				Computed:    true,
+			},
+			"name": schema.StringAttribute{
+				Description: "Name of the service",
+				Optional:    true,
+				Computed:    true,
+			},
+			"description": schema.StringAttribute{
+				Description: "Description of the service",
+				Computed:    true,
+			},
+			"versions": schema.ListAttribute{
+				Description: "Versions instances of the service can be ordered with",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"min_replica_count": schema.Int64Attribute{
+				Description: "Minimum replica count of instances of the service",
+				Computed:    true,
+			},
+			"max_replica_count": schema.Int64Attribute{
+				Description: "Maximum replica count of instances of the service",
+				Computed:    true,
+			},
+			"required_fields": schema.ListAttribute{
+				Description: "Fields an order of the service has to set",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// ConfigValidators ensures the service is looked up either by ID or by name.
+func (d *gitopsServiceDataSource) ConfigValidators(_ context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(
+			path.MatchRoot("id"),
+			path.MatchRoot("name"),
+		),
+	}
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *gitopsServiceDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state gitopsServiceModel
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var gitopsService gitopsApiService
+	if !state.Id.IsNull() {
+		service, err := d.client.GetService(ctx, uint64(state.Id.ValueInt64()))
+		if isNotFound(err) {
+			resp.Diagnostics.AddError(
+				"No gitopsService Found",
+				fmt.Sprintf("No service with ID %d exists in the catalog.", state.Id.ValueInt64()),
+			)
+			return
+		}
+		if err != nil {
+			addApiErrorDiagnostics(&resp.Diagnostics,
+				"Unable to Read gitopsService",
+				fmt.Sprintf("Could not read gitopsService ID %d", state.Id.ValueInt64()),
+				err,
+			)
+			return
+		}
+		gitopsService = service
+	} else {
+		services, err := d.client.ListServices(ctx)
+		if err != nil {
+			addApiErrorDiagnostics(&resp.Diagnostics,
+				"Unable to Read gitopsService",
+				"Could not list gitops services",
+				err,
+			)
+			return
+		}
+
+		var matches []gitopsApiService
+		for _, service := range services {
+			if service.Name == state.Name.ValueString() {
+				matches = append(matches, service)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			resp.Diagnostics.AddError(
+				"No gitopsService Found",
+				fmt.Sprintf("No service named %q exists in the catalog.", state.Name.ValueString()),
+			)
+			return
+		case 1:
+			gitopsService = matches[0]
+		default:
+			serviceIds := make([]string, 0, len(matches))
+			for _, match := range matches {
+				serviceIds = append(serviceIds, strconv.FormatUint(match.Service_id, 10))
+			}
+			resp.Diagnostics.AddError(
+				"Multiple gitopsServices Found",
+				fmt.Sprintf("The service name %q is not unique in the catalog, matching service IDs: %s. "+
+					"Look the service up by id instead.",
+					state.Name.ValueString(), strings.Join(serviceIds, ", ")),
+			)
+			return
+		}
+	}
+
+	state = newGitopsServiceModel(gitopsService)
+
+	// Set state
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *gitopsServiceDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Add a nil check when handling ProviderData because Terraform
+	// sets that data after it calls the ConfigureProvider RPC.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*gitopsApiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *gitopsApiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccGitopsServiceDataSource(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := testAccProviderConfig(api, t.TempDir())
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Look up by name and order an instance of the service
+			{
+				Config: config + `
+data "gitops_service" "redis" {
+  name = "redis"
+}
+
+resource "gitops_instance" "test" {
+  instance_name = "test-instance"
+  orderer_id    = "jane.doe@example.com"
+  bits_account  = 4711
+  service_id    = data.gitops_service.redis.id
+  replica_count = data.gitops_service.redis.min_replica_count
+  version       = data.gitops_service.redis.versions[1]
+  some_value    = "some value"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.gitops_service.redis", "id", "42"),
+					resource.TestCheckResourceAttr("data.gitops_service.redis", "description", "Redis in-memory data store"),
+					resource.TestCheckResourceAttr("data.gitops_service.redis", "versions.#", "3"),
+					resource.TestCheckResourceAttr("data.gitops_service.redis", "max_replica_count", "10"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "service_id", "42"),
+					resource.TestCheckResourceAttr("gitops_instance.test", "version", "3.2.1"),
+				),
+			},
+			{
+				Config: config + `
+data "gitops_service" "test" {
+  id   = 42
+  name = "redis"
+}
+`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+			// Look up by id
+			{
+				Config: config + `
+data "gitops_service" "postgres" {
+  id = 43
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.gitops_service.postgres", "name", "postgres"),
+					resource.TestCheckResourceAttr("data.gitops_service.postgres", "versions.#", "2"),
+					resource.TestCheckResourceAttr("data.gitops_service.postgres", "max_replica_count", "3"),
+				),
+			},
+			{
+				Config: config + `
+data "gitops_service" "test" {
+  id = 1
+}
+`,
+				ExpectError: regexp.MustCompile(`No gitopsService Found`),
+			},
+			{
+				Config: config + `
+data "gitops_service" "test" {
+  name = "does-not-exist"
+}
+`,
+				ExpectError: regexp.MustCompile(`No gitopsService Found`),
+			},
+			{
+				PreConfig: func() {
+					api.addService(gitopsApiService{Service_id: 44, Name: "redis"})
+				},
+				Config: config + `
+data "gitops_service" "test" {
+  name = "redis"
+}
+`,
+				ExpectError: regexp.MustCompile(`(?s)Multiple gitopsServices Found.*matching service IDs:\s+42, 44`),
+			},
+		},
+	})
+}
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &gitopsServicesDataSource{}
+	_ datasource.DataSourceWithConfigure = &gitopsServicesDataSource{}
+)
+
+// NewGitopsServicesDataSource is a helper function to simplify the provider implementation.
+func NewGitopsServicesDataSource() datasource.DataSource {
+	return &gitopsServicesDataSource{}
+}
+
+// gitopsServicesDataSource is the data source implementation.
+type gitopsServicesDataSource struct {
+	client *gitopsApiClient
+}
+
+// gitopsServicesDataSourceModel maps the data source schema data.
+type gitopsServicesDataSourceModel struct {
+	Services []gitopsServiceModel `tfsdk:"services"`
+}
+
+// gitopsServiceModel maps gitops catalog service schema data.
+type gitopsServiceModel struct {
+	Id                types.Int64  `tfsdk:"id"`
+	Name              types.String `tfsdk:"name"`
+	Description       types.String `tfsdk:"description"`
+	Versions          types.List   `tfsdk:"versions"`
+	Min_replica_count types.Int64  `tfsdk:"min_replica_count"`
+	Max_replica_count types.Int64  `tfsdk:"max_replica_count"`
+	Required_fields   types.List   `tfsdk:"required_fields"`
+}
+
+// newGitopsServiceModel maps a catalog service to its schema data.
+func newGitopsServiceModel(service gitopsApiService) gitopsServiceModel {
+	return gitopsServiceModel{
+		Id:                types.Int64Value(int64(service.Service_id)),
+		Name:              types.StringValue(service.Name),
+		Description:       types.StringValue(service.Description),
+		Versions:          stringListValue(service.Versions),
+		Min_replica_count: types.Int64Value(int64(service.Min_replica_count)),
+		Max_replica_count: types.Int64Value(int64(service.Max_replica_count)),
+		Required_fields:   stringListValue(service.Required_fields),
+	}
+}
+
+// Metadata returns the data source type name.
+func (d *gitopsServicesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_services"
+}
+
+// Schema defines the schema for the data source.
+func (d *gitopsServicesDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the services of the Gitops API catalog",
+		Attributes: map[string]schema.Attribute{
+			"services": schema.ListNestedAttribute{
+				Description: "Services of the catalog",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.Int64Attribute{
+							Description: "Service-ID of the service, the service_id of its instances",
+							Computed:    true,
+						},
+						"name": schema.StringAttribute{
+							Description: "Name of the service",
+							Computed:    true,
+						},
+						"description": schema.StringAttribute{
+							Description: "Description of the service",
+							Computed:    true,
+						},
+						"versions": schema.ListAttribute{
+							Description: "Versions instances of the service can be ordered with",
+							ElementType: types.StringType,
+							Computed:    true,
+						},
+						"min_replica_count": schema.Int64Attribute{
+							Description: "Minimum replica count of instances of the service",
+							Computed:    true,
+						},
+						"max_replica_count": schema.Int64Attribute{
+							Description: "Maximum replica count of instances of the service",
+							Computed:    true,
+						},
+						"required_fields": schema.ListAttribute{
+							Description: "Fields an order of the service has to set",
+							ElementType: types.StringType,
+							Computed:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *gitopsServicesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state gitopsServicesDataSourceModel
+
+	services, err := d.client.ListServices(ctx)
+	if err != nil {
+		addApiErrorDiagnostics(&resp.Diagnostics,
+			"Unable to Read gitops Services",
+			"Could not list gitops services",
+			err,
+		)
+		return
+	}
+
+	state.Services = make([]gitopsServiceModel, 0, len(services))
+	for _, service := range services {
+		state.Services = append(state.Services, newGitopsServiceModel(service))
+	}
+
+	// Set state
+	diags := resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *gitopsServicesDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Add a nil check when handling ProviderData because Terraform
+	// sets that data after it calls the ConfigureProvider RPC.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*gitopsApiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *gitopsApiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+// stringListValue converts a string slice into a known list value.
+func stringListValue(values []string) types.List {
+	elements := make([]attr.Value, 0, len(values))
+	for _, value := range values {
+		elements = append(elements, types.StringValue(value))
+	}
+	return types.ListValueMust(types.StringType, elements)
+}
@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccGitopsServicesDataSource(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, t.TempDir()) + `
+data "gitops_services" "all" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.#", "2"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.id", "42"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.name", "redis"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.description", "Redis in-memory data store"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.versions.#", "3"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.versions.2", "3.3.0"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.min_replica_count", "1"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.max_replica_count", "10"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.required_fields.#", "1"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.0.required_fields.0", "some_value"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.1.name", "postgres"),
+					resource.TestCheckResourceAttr("data.gitops_services.all", "services.1.required_fields.#", "0"),
+				),
+			},
+		},
+	})
+}
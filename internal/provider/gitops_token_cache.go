@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Supported values of cache_mode.
+const (
+	cacheModeDisk   = "disk"
+	cacheModeMemory = "memory"
+	cacheModeNone   = "none"
+)
+
+// cacheModes are the supported values of cache_mode.
+var cacheModes = []string{
+	cacheModeDisk,
+	cacheModeMemory,
+	cacheModeNone,
+}
+
+// cachedTokens are the tokens cached for one identity.
+type cachedTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenCache stores the tokens of a single identity.
+type tokenCache interface {
+	// lock serializes token renewals of the identity across provider
+	// instances and Terraform processes sharing the cache. The returned
+	// function releases the lock.
+	lock() (func(), error)
+	// load returns the cached tokens, which are empty if nothing is cached.
+	load() (cachedTokens, error)
+	// store replaces the cached tokens.
+	store(tokens cachedTokens) error
+}
+
+// tokenCacheIdentity identifies whose tokens are cached. Provider
+// configurations only share tokens if all of its values match.
+type tokenCacheIdentity struct {
+	// Issuer is the token endpoint issuing the tokens
+	Issuer   string
+	ClientId string
+	// Audience is the Gitops API the tokens are used for
+	Audience    string
+	Environment string
+	Scopes      string
+	GrantType   string
+	// Username is only set for the password grant
+	Username string
+}
+
+// key derives a file name safe cache key from the identity.
+func (i tokenCacheIdentity) key() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{i.Issuer, i.ClientId, i.Audience, i.Environment, i.Scopes, i.GrantType, i.Username}, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// newTokenCache returns the token cache of identity for cache_mode mode.
+func newTokenCache(mode string, cachePath string, identity tokenCacheIdentity) tokenCache {
+	switch mode {
+	case cacheModeMemory:
+		return memoryTokenCache{key: identity.key()}
+	case cacheModeNone:
+		return noTokenCache{}
+	default:
+		return diskTokenCache{
+			path: filepath.Join(cachePath, "token-"+identity.key()+".json"),
+		}
+	}
+}
+
+// diskTokenCache keeps the tokens in a JSON file only readable by the
+// current user, locked by a separate lock file.
+type diskTokenCache struct {
+	path string
+}
+
+func (c diskTokenCache) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(c.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+func (c diskTokenCache) load() (cachedTokens, error) {
+	var tokens cachedTokens
+	content, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return tokens, err
+	}
+	err = json.Unmarshal(content, &tokens)
+	return tokens, err
+}
+
+// store writes the tokens to a temporary file first and renames it, so
+// readers never see a partially written cache file.
+func (c diskTokenCache) store(tokens cachedTokens) error {
+	content, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	// CreateTemp creates the file with 0600 permissions
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.path)
+}
+
+// memoryTokens are the tokens of all memoryTokenCaches, shared by the
+// provider configurations of a single provider process.
+var memoryTokens = struct {
+	sync.Mutex
+	entries map[string]cachedTokens
+	locks   map[string]*sync.Mutex
+}{
+	entries: map[string]cachedTokens{},
+	locks:   map[string]*sync.Mutex{},
+}
+
+// memoryTokenCache keeps the tokens in memory for the lifetime of the
+// provider process.
+type memoryTokenCache struct {
+	key string
+}
+
+func (c memoryTokenCache) lock() (func(), error) {
+	memoryTokens.Lock()
+	lock, ok := memoryTokens.locks[c.key]
+	if !ok {
+		lock = &sync.Mutex{}
+		memoryTokens.locks[c.key] = lock
+	}
+	memoryTokens.Unlock()
+
+	lock.Lock()
+	return lock.Unlock, nil
+}
+
+func (c memoryTokenCache) load() (cachedTokens, error) {
+	memoryTokens.Lock()
+	defer memoryTokens.Unlock()
+	return memoryTokens.entries[c.key], nil
+}
+
+func (c memoryTokenCache) store(tokens cachedTokens) error {
+	memoryTokens.Lock()
+	defer memoryTokens.Unlock()
+	memoryTokens.entries[c.key] = tokens
+	return nil
+}
+
+// noTokenCache does not cache tokens, every provider configuration obtains
+// its own tokens.
+type noTokenCache struct{}
+
+func (noTokenCache) lock() (func(), error) {
+	return func() {}, nil
+}
+
+func (noTokenCache) load() (cachedTokens, error) {
+	return cachedTokens{}, nil
+}
+
+func (noTokenCache) store(cachedTokens) error {
+	return nil
+}
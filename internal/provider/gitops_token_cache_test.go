@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDiskTokenCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache")
+	cache := newTokenCache(cacheModeDisk, cachePath, tokenCacheIdentity{ClientId: "terraform"})
+
+	if cached, err := cache.load(); err != nil || cached != (cachedTokens{}) {
+		t.Fatalf("got (%+v, %v) from an empty cache, want no tokens", cached, err)
+	}
+	tokens := cachedTokens{AccessToken: "access", RefreshToken: "refresh"}
+	if err := cache.store(tokens); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := cache.load(); err != nil || cached != tokens {
+		t.Fatalf("got (%+v, %v), want %+v", cached, err, tokens)
+	}
+
+	entries, err := os.ReadDir(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d files in the cache, want only the token file", len(entries))
+	}
+	if runtime.GOOS != "windows" {
+		info, err := entries[0].Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("got token file mode %o, want 600", mode)
+		}
+	}
+}
+
+func TestTokenCacheKeysByIdentity(t *testing.T) {
+	identity := tokenCacheIdentity{
+		Issuer:      "https://idp.example.com/token",
+		ClientId:    "terraform",
+		Audience:    "https://gitops.example.com",
+		Environment: "prod",
+		GrantType:   grantTypePassword,
+		Username:    "jane",
+	}
+	otherUser := identity
+	otherUser.Username = "john"
+	otherEnvironment := identity
+	otherEnvironment.Environment = "dev"
+
+	for _, mode := range []string{cacheModeDisk, cacheModeMemory} {
+		cachePath := t.TempDir()
+		cache := newTokenCache(mode, cachePath, identity)
+		if err := cache.store(cachedTokens{AccessToken: "jane"}); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, other := range []tokenCacheIdentity{otherUser, otherEnvironment} {
+			cached, err := newTokenCache(mode, cachePath, other).load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cached.AccessToken != "" {
+				t.Errorf("%s: got the access token of %+v for %+v", mode, identity, other)
+			}
+		}
+		cached, err := newTokenCache(mode, cachePath, identity).load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cached.AccessToken != "jane" {
+			t.Errorf("%s: got access token %q, want the stored one", mode, cached.AccessToken)
+		}
+	}
+}
+
+func TestNoTokenCache(t *testing.T) {
+	cache := newTokenCache(cacheModeNone, "", tokenCacheIdentity{})
+	if err := cache.store(cachedTokens{AccessToken: "access"}); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := cache.load(); err != nil || cached != (cachedTokens{}) {
+		t.Errorf("got (%+v, %v), want no tokens", cached, err)
+	}
+}
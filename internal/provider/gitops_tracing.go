@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// gitopsApiLogSubsystem is the tflog subsystem of the request traces
+// written in debug mode.
+const gitopsApiLogSubsystem = "gitops_api"
+
+// redactedValue replaces the values of sensitive keys in traced bodies.
+const redactedValue = "***"
+
+// enableTracing logs every request of the client, to the Gitops API as
+// well as to the token endpoint, with its response to the gitops_api log
+// subsystem. Credentials in the bodies are redacted, headers are not
+// logged at all as they hold the access token.
+func (c *gitopsApiClient) enableTracing() {
+	c.RestyClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		fields := requestTraceFields(resp.Request)
+		fields["status"] = resp.StatusCode()
+		fields["latency"] = resp.Time().String()
+		if body := redactBody(resp.Body()); body != "" {
+			fields["response_body"] = body
+		}
+		tflog.SubsystemDebug(c.traceContext(resp.Request.Context()), gitopsApiLogSubsystem, "Gitops API request", fields)
+		return nil
+	})
+	c.RestyClient.OnError(func(req *resty.Request, err error) {
+		fields := requestTraceFields(req)
+		fields["latency"] = time.Since(req.Time).String()
+		fields["error"] = err.Error()
+		tflog.SubsystemDebug(c.traceContext(req.Context()), gitopsApiLogSubsystem, "Gitops API request failed", fields)
+	})
+}
+
+// traceContext returns ctx with the gitops_api log subsystem, masking the
+// same credentials as maskCredentials and naming the environment, if any.
+func (c *gitopsApiClient) traceContext(ctx context.Context) context.Context {
+	ctx = tflog.NewSubsystem(ctx, gitopsApiLogSubsystem)
+	ctx = tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, gitopsApiLogSubsystem, sensitiveLogFieldKeys...)
+	ctx = tflog.SubsystemMaskAllFieldValuesRegexes(ctx, gitopsApiLogSubsystem, jwtPattern)
+	ctx = tflog.SubsystemMaskMessageRegexes(ctx, gitopsApiLogSubsystem, jwtPattern)
+	if secrets := c.secrets(); len(secrets) > 0 {
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, gitopsApiLogSubsystem, secrets...)
+		ctx = tflog.SubsystemMaskMessageStrings(ctx, gitopsApiLogSubsystem, secrets...)
+	}
+	if c.environment != "" {
+		ctx = tflog.SubsystemSetField(ctx, gitopsApiLogSubsystem, "gitops_environment", c.environment)
+	}
+	return ctx
+}
+
+// requestTraceFields returns the log fields describing req.
+func requestTraceFields(req *resty.Request) map[string]any {
+	fields := map[string]any{
+		"method": req.Method,
+		"url":    req.URL,
+	}
+	if len(req.FormData) > 0 {
+		fields["request_body"] = redactForm(req.FormData)
+	} else if req.Body != nil {
+		if body, err := json.Marshal(req.Body); err == nil {
+			fields["request_body"] = redactBody(body)
+		}
+	}
+	return fields
+}
+
+// redactForm encodes a form body with the values of sensitive keys
+// redacted.
+func redactForm(form url.Values) string {
+	redacted := url.Values{}
+	for key, values := range form {
+		if slices.Contains(sensitiveLogFieldKeys, key) {
+			values = []string{redactedValue}
+		}
+		redacted[key] = values
+	}
+	return redacted.Encode()
+}
+
+// redactBody returns a JSON body with the values of sensitive keys
+// redacted at any depth. Other bodies are returned as is, left to the
+// masking of the log subsystem.
+func redactBody(body []byte) string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+// redactValue redacts the values of sensitive keys in a decoded JSON value.
+func redactValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, nested := range value {
+			if slices.Contains(sensitiveLogFieldKeys, key) {
+				value[key] = redactedValue
+			} else {
+				value[key] = redactValue(nested)
+			}
+		}
+	case []any:
+		for i, nested := range value {
+			value[i] = redactValue(nested)
+		}
+	}
+	return value
+}
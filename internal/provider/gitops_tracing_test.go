@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestGitopsApiClientTracesRequests(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	client := newFakeApiClient(t, api, grantTypeClientCredentials)
+	// The password would be masked within "client_secret"
+	client.Password = ""
+	client.enableTracing()
+
+	var out bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &out)
+	if _, err := client.ListInstances(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if strings.Contains(out.String(), client.AccessToken) || strings.Contains(out.String(), fakeClientSecret) {
+		t.Errorf("credentials were traced: %s", out.String())
+	}
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	traces := map[string]map[string]any{}
+	for _, entry := range entries {
+		if entry["@module"] == "provider."+gitopsApiLogSubsystem {
+			traces[entry["url"].(string)] = entry
+		}
+	}
+
+	token := traces[api.URL+"/token"]
+	if token == nil {
+		t.Fatalf("token request was not traced: %v", entries)
+	}
+	if token["method"] != "POST" || token["status"] != float64(200) || token["latency"] == nil {
+		t.Errorf("got token request trace %v, want method, status and latency", token)
+	}
+	if body, _ := token["request_body"].(string); !strings.Contains(body, "client_secret=%2A%2A%2A") {
+		t.Errorf("got token request body %q, want the client_secret redacted", body)
+	}
+	if body, _ := token["response_body"].(string); !strings.Contains(body, `"access_token":"***"`) {
+		t.Errorf("got token response body %q, want the access_token redacted", body)
+	}
+
+	instances := traces[api.URL+"/instances"]
+	if instances == nil || instances["method"] != "GET" || instances["status"] != float64(200) {
+		t.Errorf("got instance list trace %v, want the GET request", instances)
+	}
+}
+
+func TestGitopsApiClientTracesDeviceCodeRedacted(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	client := newFakeApiClient(t, api, grantTypeDeviceCode)
+	client.enableTracing()
+
+	var out bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &out)
+	// The fake token endpoint rejects the grant, the request is traced anyway
+	_, err := client.RestyClient.R().SetContext(ctx).SetFormData(map[string]string{
+		"grant_type":  "urn:ietf:params:oauth:grant-type:device_code",
+		"device_code": "secret-device-code",
+		"client_id":   fakeClientId,
+	}).Post(api.URL + "/token")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if strings.Contains(out.String(), "secret-device-code") {
+		t.Errorf("device code was traced: %s", out.String())
+	}
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body string
+	for _, entry := range entries {
+		if entry["@module"] == "provider."+gitopsApiLogSubsystem && entry["url"] == api.URL+"/token" {
+			body, _ = entry["request_body"].(string)
+		}
+	}
+	if !strings.Contains(body, "device_code=%2A%2A%2A") || !strings.Contains(body, "grant-type%3Adevice_code") {
+		t.Errorf("got token request body %q, want the device_code redacted and the grant_type kept", body)
+	}
+}
+
+func TestRedactForm(t *testing.T) {
+	got := redactForm(url.Values{"code": {"secret"}, "redirect_uri": {"http://localhost:8080"}})
+	want := "code=%2A%2A%2A&redirect_uri=http%3A%2F%2Flocalhost%3A8080"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	got := redactBody([]byte(`{"items":[{"refresh_token":"secret","name":"a"}],"password":"secret"}`))
+	want := `{"items":[{"name":"a","refresh_token":"***"}],"password":"***"}`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if got := redactBody([]byte("plain text")); got != "plain text" {
+		t.Errorf("got %q, want non-JSON bodies as is", got)
+	}
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ basetypes.StringTypable                    = jsonObjectType{}
+	_ basetypes.StringValuableWithSemanticEquals = jsonObjectValue{}
+)
+
+// jsonObjectType is a string attribute type holding a JSON-encoded object,
+// e.g. the result of jsonencode. Values differing only in formatting or
+// key order are semantically equal.
+type jsonObjectType struct {
+	basetypes.StringType
+}
+
+// String returns a human readable string of the type name.
+func (t jsonObjectType) String() string {
+	return "jsonObjectType"
+}
+
+// ValueType returns the Value type.
+func (t jsonObjectType) ValueType(_ context.Context) attr.Value {
+	return jsonObjectValue{}
+}
+
+// Equal returns true if the given type is equivalent.
+func (t jsonObjectType) Equal(o attr.Type) bool {
+	other, ok := o.(jsonObjectType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+// ValueFromString returns a StringValuable type given a StringValue.
+func (t jsonObjectType) ValueFromString(_ context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return jsonObjectValue{StringValue: in}, nil
+}
+
+// ValueFromTerraform returns a Value given a tftypes.Value.
+func (t jsonObjectType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+	return jsonObjectValue{StringValue: stringValue}, nil
+}
+
+// jsonObjectValue is a value of jsonObjectType.
+type jsonObjectValue struct {
+	basetypes.StringValue
+}
+
+// newJsonObjectValue returns a known value holding the encoded object.
+func newJsonObjectValue(encoded string) jsonObjectValue {
+	return jsonObjectValue{StringValue: basetypes.NewStringValue(encoded)}
+}
+
+// Type returns the type of the value.
+func (v jsonObjectValue) Type(_ context.Context) attr.Type {
+	return jsonObjectType{}
+}
+
+// Equal returns true if the given value is equivalent, including its
+// formatting.
+func (v jsonObjectValue) Equal(o attr.Value) bool {
+	other, ok := o.(jsonObjectValue)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+// StringSemanticEquals returns true if both values encode the same object,
+// so that the Gitops API may reformat the object without causing a diff.
+func (v jsonObjectValue) StringSemanticEquals(_ context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	newValue, ok := newValuable.(jsonObjectValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T, got: %T. Please report this issue to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	var current, updated any
+	if err := json.Unmarshal([]byte(v.ValueString()), &current); err != nil {
+		return false, diags
+	}
+	if err := json.Unmarshal([]byte(newValue.ValueString()), &updated); err != nil {
+		return false, diags
+	}
+	return reflect.DeepEqual(current, updated), diags
+}
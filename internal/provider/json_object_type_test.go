@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestJsonObjectSemanticEquals(t *testing.T) {
+	value := newJsonObjectValue(`{"replicas": 3, "storage": {"size": "1Gi", "class": "ssd"}}`)
+
+	equal := []string{
+		`{"replicas":3,"storage":{"size":"1Gi","class":"ssd"}}`,
+		`{"storage": {"class": "ssd", "size": "1Gi"}, "replicas": 3.0}`,
+	}
+	for _, other := range equal {
+		got, diags := value.StringSemanticEquals(context.Background(), newJsonObjectValue(other))
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		if !got {
+			t.Errorf("%s: want semantically equal", other)
+		}
+	}
+
+	different := []string{
+		`{"replicas": 4, "storage": {"size": "1Gi", "class": "ssd"}}`,
+		`{"replicas": 3, "storage": {"size": "1Gi"}}`,
+		`{"replicas": "3", "storage": {"size": "1Gi", "class": "ssd"}}`,
+		`not json`,
+	}
+	for _, other := range different {
+		got, diags := value.StringSemanticEquals(context.Background(), newJsonObjectValue(other))
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		if got {
+			t.Errorf("%s: want semantically different", other)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// instanceIdRegexp matches the IDs the Gitops API assigns to instances.
+var instanceIdRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ function.Function = &parseInstanceIdFunction{}
+
+// NewParseInstanceIdFunction is a helper function to simplify the provider implementation.
+func NewParseInstanceIdFunction() function.Function {
+	return &parseInstanceIdFunction{}
+}
+
+// parseInstanceIdFunction is the function implementation.
+type parseInstanceIdFunction struct{}
+
+// Metadata returns the function name.
+func (f *parseInstanceIdFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "parse_instance_id"
+}
+
+// Definition defines the parameters and return type of the function.
+func (f *parseInstanceIdFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Extracts a Gitops instance ID",
+		Description: "Returns the instance ID of a bare instance ID like \"123\", an instance path like \"/instances/123\" " +
+			"or an instance URI like \"https://gitops.example.com/instances/123\", e.g. to import instances referenced by URI.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "id",
+				Description: "Instance ID, path or URI",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+// Run extracts the instance ID.
+func (f *parseInstanceIdFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var id string
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &id))
+	if resp.Error != nil {
+		return
+	}
+
+	instanceId, ok := parseInstanceId(id)
+	if !ok {
+		resp.Error = function.NewArgumentFuncError(0, "\""+id+"\" is neither an instance ID nor the path or URI of an instance")
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, instanceId))
+}
+
+// parseInstanceId extracts the instance ID of a bare ID, an instance path
+// or an instance URI.
+func parseInstanceId(id string) (string, bool) {
+	if instanceIdRegexp.MatchString(id) {
+		return id, true
+	}
+	if uri, err := url.Parse(id); err == nil {
+		id = uri.Path
+	}
+	segments := strings.Split(strings.Trim(id, "/"), "/")
+	if len(segments) < 2 || segments[len(segments)-2] != "instances" {
+		return "", false
+	}
+	instanceId := segments[len(segments)-1]
+	if !instanceIdRegexp.MatchString(instanceId) {
+		return "", false
+	}
+	return instanceId, true
+}
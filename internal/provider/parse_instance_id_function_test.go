@@ -0,0 +1,25 @@
+package provider
+
+import "testing"
+
+func TestParseInstanceId(t *testing.T) {
+	tests := map[string]string{
+		"123":            "123",
+		"a1b2-c3_d4":     "a1b2-c3_d4",
+		"/instances/123": "123",
+		"instances/123/": "123",
+		"https://gitops.example.com/api/instances/123":  "123",
+		"http://localhost:8000/instances/123?stage=dev": "123",
+		"":              "",
+		"instances/":    "",
+		"/services/123": "",
+		"123 456":       "",
+		"https://gitops.example.com/instances/12%203": "",
+	}
+	for id, want := range tests {
+		got, ok := parseInstanceId(id)
+		if got != want || ok != (want != "") {
+			t.Errorf("%q: got (%q, %t), want %q", id, got, ok, want)
+		}
+	}
+}
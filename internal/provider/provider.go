@@ -2,13 +2,23 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"os"
-
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
@@ -17,7 +27,8 @@ import (
 
 // Ensure the implementation satisfies the expected interfaces.
 var (
-	_ provider.Provider = &gitopsProvider{}
+	_ provider.Provider              = &gitopsProvider{}
+	_ provider.ProviderWithFunctions = &gitopsProvider{}
 )
 
 // New is a helper function to simplify provider server and testing implementation.
@@ -31,20 +42,30 @@ func New(version string) func() provider.Provider {
 
 // gitopsProviderModel maps provider schema data to a Go type.
 type gitopsProviderModel struct {
-	GitopsApiURI        types.String `tfsdk:"gitops_api_uri"`
-	CachePath           types.String `tfsdk:"cache_path"`
-	Username            types.String `tfsdk:"username"`
-	Password            types.String `tfsdk:"password"`
-	ClientId            types.String `tfsdk:"client_id"`
-	ClientSecret        types.String `tfsdk:"client_secret"`
-	TokenURI            types.String `tfsdk:"token_uri"`
-	JwksURI             types.String `tfsdk:"jwks_uri"`
-	AuthURI             types.String `tfsdk:"auth_uri"`
-	RedirectURI         types.String `tfsdk:"redirect_uri"`
-	AuthzListenerSocket types.String `tfsdk:"authz_listener_socket"`
-	Scopes              types.String `tfsdk:"scopes"`
-	GrantType           types.String `tfsdk:"grant_type"`
-	Debug               types.Bool   `tfsdk:"debug"`
+	GitopsApiURI                types.String `tfsdk:"gitops_api_uri"`
+	Environment                 types.String `tfsdk:"environment"`
+	CachePath                   types.String `tfsdk:"cache_path"`
+	CacheMode                   types.String `tfsdk:"cache_mode"`
+	AccessToken                 types.String `tfsdk:"access_token"`
+	Username                    types.String `tfsdk:"username"`
+	Password                    types.String `tfsdk:"password"`
+	ClientId                    types.String `tfsdk:"client_id"`
+	ClientSecret                types.String `tfsdk:"client_secret"`
+	TokenURI                    types.String `tfsdk:"token_uri"`
+	JwksURI                     types.String `tfsdk:"jwks_uri"`
+	AuthURI                     types.String `tfsdk:"auth_uri"`
+	RedirectURI                 types.String `tfsdk:"redirect_uri"`
+	AuthzListenerSocket         types.String `tfsdk:"authz_listener_socket"`
+	Scopes                      types.String `tfsdk:"scopes"`
+	GrantType                   types.String `tfsdk:"grant_type"`
+	IdToken                     types.String `tfsdk:"id_token"`
+	IdTokenFile                 types.String `tfsdk:"id_token_file"`
+	Debug                       types.Bool   `tfsdk:"debug"`
+	MaxRetries                  types.Int64  `tfsdk:"max_retries"`
+	RetryMinWait                types.String `tfsdk:"retry_min_wait"`
+	RetryMaxWait                types.String `tfsdk:"retry_max_wait"`
+	ImmutableInstanceAttributes types.List   `tfsdk:"immutable_instance_attributes"`
+	DefaultLabels               types.Map    `tfsdk:"default_labels"`
 }
 
 // gitopsProvider is the provider implementation.
@@ -70,13 +91,62 @@ func (p *gitopsProvider) Schema(_ context.Context, _ provider.SchemaRequest, res
 				Description: "URI for Gitops API. May also be provided via GITOPS_HOST environment variable.",
 				Required:    true,
 			},
+			"environment": schema.StringAttribute{
+				Description: "Environment (stage) of the Gitops API, e.g. dev or prod. If set, the provider fails unless the Gitops API reports the same environment, " +
+					"sends it with every instance order and keeps its cached tokens and logs apart from other environments. " +
+					"May also be provided via GITOPS_ENVIRONMENT environment variable.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
 			"cache_path": schema.StringAttribute{
-				Description: "Gitops client cache_path. May also be provided via GITOPS_CACHEPATH environment variable.",
-				Required:    true,
+				Description: "Directory the tokens are cached in with cache_mode disk. Required for cache_mode disk unless access_token is set, optional for grant_type client_credentials. " +
+					"The idempotency keys of orders with an unknown outcome are kept there as well, so the next apply does not order them twice. May also be provided via GITOPS_CACHEPATH environment variable.",
+				Optional: true,
+			},
+			"cache_mode": schema.StringAttribute{
+				Description: "Where the tokens are cached, one of disk, memory and none. Defaults to memory for grant_type client_credentials without cache_path, to disk otherwise. disk shares the tokens with other Terraform runs " +
+					"and provider configurations using the same token_uri, client_id, gitops_api_uri, scopes, grant_type and username, memory only with the " +
+					"provider configurations of the same Terraform run, none does not share them. May also be provided via GITOPS_CACHE_MODE environment variable.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(cacheModes...),
+				},
+			},
+			"access_token": schema.StringAttribute{
+				Description: fmt.Sprintf("Pre-issued access token for the Gitops API, e.g. from a secrets manager. "+
+					"Replaces the OAuth2 configuration, which is ignored if set. The token has to be valid for at least %s, "+
+					"as it cannot be renewed while Terraform runs. May also be provided via GITOPS_TOKEN environment variable.",
+					minStaticAccessTokenValidity),
+				Optional:  true,
+				Sensitive: true,
 			},
 			"grant_type": schema.StringAttribute{
-				Description: "Gitops client grant_type (oauth). May also be provided via GITOPS_GRANTTYPE environment variable.",
-				Required:    true,
+				Description: "Gitops client grant_type (oauth), required unless access_token is set, one of password, auth_code, device_code, client_credentials, token_exchange and jwt_bearer. " +
+					"client_credentials authenticates the client itself without user interaction, e.g. in CI pipelines. " +
+					"token_exchange (RFC 8693) and jwt_bearer (RFC 7523) exchange the OIDC ID token of a workload like a CI job, " +
+					"given as id_token or id_token_file, for an access token. " +
+					"May also be provided via GITOPS_GRANTTYPE environment variable.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(grantTypes...),
+				},
+			},
+			"id_token": schema.StringAttribute{
+				Description: "OIDC ID token of the workload (oauth grant_type: token_exchange and jwt_bearer). " +
+					"May also be provided via GITOPS_ID_TOKEN environment variable.",
+				Optional:  true,
+				Sensitive: true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("id_token_file")),
+				},
+			},
+			"id_token_file": schema.StringAttribute{
+				Description: "Path of a file holding the OIDC ID token of the workload (oauth grant_type: token_exchange and jwt_bearer). " +
+					"The file is read again whenever a new access token is requested, so it may be replaced while Terraform runs. " +
+					"May also be provided via GITOPS_ID_TOKEN_FILE environment variable.",
+				Optional: true,
 			},
 			"username": schema.StringAttribute{
 				Description: "Gitops client username (oauth grant_type: password). May also be provided via GITOPS_USERNAME environment variable.",
@@ -88,32 +158,33 @@ func (p *gitopsProvider) Schema(_ context.Context, _ provider.SchemaRequest, res
 				Sensitive:   true,
 			},
 			"client_id": schema.StringAttribute{
-				Description: "Gitops client client_id (oauth). May also be provided via GITOPS_CLIENTID environment variable.",
-				Required:    true,
+				Description: "Gitops client client_id (oauth), required unless access_token is set. May also be provided via GITOPS_CLIENTID environment variable.",
+				Optional:    true,
 			},
 			"client_secret": schema.StringAttribute{
-				Description: "Gitops client client_secret (oauth). May also be provided via GITOPS_CLIENTSECRET environment variable.",
+				Description: "Gitops client client_secret (oauth), required for grant_type client_credentials. May also be provided via GITOPS_CLIENTSECRET environment variable.",
 				Optional:    true,
 				Sensitive:   true,
 			},
 			"token_uri": schema.StringAttribute{
-				Description: "Gitops client token_uri (oauth). May also be provided via GITOPS_TOKENURI environment variable.",
-				Required:    true,
+				Description: "Gitops client token_uri (oauth), required unless access_token is set. May also be provided via GITOPS_TOKENURI environment variable.",
+				Optional:    true,
 			},
 			"auth_uri": schema.StringAttribute{
-				Description: "Gitops client auth_uri (oauth). May also be provided via GITOPS_AUTHURI environment variable.",
-				Required:    true,
+				Description: "Gitops client auth_uri (oauth), required for grant_type auth_code and device_code. May also be provided via GITOPS_AUTHURI environment variable.",
+				Optional:    true,
 			},
 			"jwks_uri": schema.StringAttribute{
-				Description: "Gitops client jwks_uri (oauth). May also be provided via GITOPS_JWKSURI environment variable.",
-				Required:    true,
+				Description: "Gitops client jwks_uri (oauth) to verify cached access tokens with. Without it, only the expiry of cached access tokens is checked. " +
+					"May also be provided via GITOPS_JWKSURI environment variable.",
+				Optional: true,
 			},
 			"redirect_uri": schema.StringAttribute{
-				Description: "Gitops client redirect_uri (oauth). May also be provided via GITOPS_REDIRECTURI environment variable.",
+				Description: "Gitops client redirect_uri (oauth), required for grant_type auth_code. May also be provided via GITOPS_REDIRECTURI environment variable.",
 				Optional:    true,
 			},
 			"authz_listener_socket": schema.StringAttribute{
-				Description: "Gitops client http server for  (oauth grant_type: password). May also be provided via GITOPS_AUTHZLISTENERSOCKET environment variable.",
+				Description: "Gitops client http server for the authorization code callback (oauth), required for grant_type auth_code. May also be provided via GITOPS_AUTHZLISTENERSOCKET environment variable.",
 				Optional:    true,
 			},
 			"scopes": schema.StringAttribute{
@@ -121,7 +192,41 @@ func (p *gitopsProvider) Schema(_ context.Context, _ provider.SchemaRequest, res
 				Optional:    true,
 			},
 			"debug": schema.BoolAttribute{
-				Description: "Gitops client debug mode. May also be provided via GITOPS_DEBUG environment variable.",
+				Description: "Gitops client debug mode, tracing every request to the Gitops API and the token endpoint with its method, URL, status, latency " +
+					"and bodies, credentials redacted, in the gitops_api log subsystem. The traces are logged at the DEBUG level, e.g. with TF_LOG=DEBUG. " +
+					"May also be provided via GITOPS_DEBUG environment variable.",
+				Optional: true,
+			},
+			"max_retries": schema.Int64Attribute{
+				Description: "Maximum number of retries of transient Gitops API failures (connection errors, HTTP 429, 500, 502, 503 and 504). " +
+					"Instance orders are only retried when sent with an idempotency key. Defaults to 3. " +
+					"May also be provided via GITOPS_MAX_RETRIES environment variable.",
+				Optional: true,
+			},
+			"retry_min_wait": schema.StringAttribute{
+				Description: "Minimum wait before retrying a failed Gitops API request, as a duration like \"1s\". Defaults to 1s. " +
+					"May also be provided via GITOPS_RETRY_MIN_WAIT environment variable.",
+				Optional: true,
+			},
+			"retry_max_wait": schema.StringAttribute{
+				Description: "Maximum wait before retrying a failed Gitops API request, as a duration like \"30s\". Defaults to 30s. " +
+					"May also be provided via GITOPS_RETRY_MAX_WAIT environment variable.",
+				Optional: true,
+			},
+			"immutable_instance_attributes": schema.ListAttribute{
+				Description: "Attributes of gitops_instance the Gitops API cannot update in place, changing them replaces the instance. " +
+					"Supports bits_account and service_id, orderer_id is always immutable. " +
+					"May also be provided as comma separated list via GITOPS_IMMUTABLE_INSTANCE_ATTRIBUTES environment variable.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(stringvalidator.OneOf(mutableInstanceAttributes...)),
+				},
+			},
+			"default_labels": schema.MapAttribute{
+				Description: "Labels added to every gitops_instance, e.g. its team or cost center. The labels of an instance override default labels with the same key. " +
+					"May also be provided as comma separated key=value list via GITOPS_DEFAULT_LABELS environment variable.",
+				ElementType: types.StringType,
 				Optional:    true,
 			},
 		},
@@ -148,6 +253,14 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API gitops_api_uri.",
 		)
 	}
+	if config.Environment.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("environment"),
+			"Unknown gitops API environment",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API environment.",
+		)
+	}
+
 	if config.CachePath.IsUnknown() {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("cache_path"),
@@ -155,6 +268,22 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API cache_path.",
 		)
 	}
+	if config.CacheMode.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("cache_mode"),
+			"Unknown gitops API cache_mode",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API cache_mode.",
+		)
+	}
+
+	if config.AccessToken.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("access_token"),
+			"Unknown gitops API access_token",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API access_token.",
+		)
+	}
+
 	if config.Username.IsUnknown() {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("username"),
@@ -243,6 +372,22 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 		)
 	}
 
+	if config.IdToken.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("id_token"),
+			"Unknown gitops API id_token",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API id_token.",
+		)
+	}
+
+	if config.IdTokenFile.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("id_token_file"),
+			"Unknown gitops API id_token_file",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API id_token_file.",
+		)
+	}
+
 	if config.Debug.IsUnknown() {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("debug"),
@@ -251,6 +396,46 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 		)
 	}
 
+	if config.MaxRetries.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_retries"),
+			"Unknown gitops API max_retries",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API max_retries.",
+		)
+	}
+
+	if config.RetryMinWait.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("retry_min_wait"),
+			"Unknown gitops API retry_min_wait",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API retry_min_wait.",
+		)
+	}
+
+	if config.RetryMaxWait.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("retry_max_wait"),
+			"Unknown gitops API retry_max_wait",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API retry_max_wait.",
+		)
+	}
+
+	if config.ImmutableInstanceAttributes.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("immutable_instance_attributes"),
+			"Unknown gitops API immutable_instance_attributes",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API immutable_instance_attributes.",
+		)
+	}
+
+	if config.DefaultLabels.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("default_labels"),
+			"Unknown gitops API default_labels",
+			"The provider cannot create the gitops API client as there is an unknown configuration value for the gitops API default_labels.",
+		)
+	}
+
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -259,7 +444,10 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 	// with Terraform configuration value if set.
 
 	gitops_api_uri := os.Getenv("GITOPS_HOST")
+	environment := os.Getenv("GITOPS_ENVIRONMENT")
 	cache_path := os.Getenv("GITOPS_CACHEPATH")
+	cache_mode := os.Getenv("GITOPS_CACHE_MODE")
+	access_token := os.Getenv("GITOPS_TOKEN")
 	username := os.Getenv("GITOPS_USERNAME")
 	password := os.Getenv("GITOPS_PASSWORD")
 	client_id := os.Getenv("GITOPS_CLIENTID")
@@ -271,15 +459,54 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 	authz_listener_socket := os.Getenv("GITOPS_AUTHZLISTENERSOCKET")
 	scopes := os.Getenv("GITOPS_SCOPES")
 	grant_type := os.Getenv("GITOPS_GRANTTYPE")
+	id_token := os.Getenv("GITOPS_ID_TOKEN")
+	id_token_file := os.Getenv("GITOPS_ID_TOKEN_FILE")
+	debug := os.Getenv("GITOPS_DEBUG")
+	max_retries := os.Getenv("GITOPS_MAX_RETRIES")
+	retry_min_wait := os.Getenv("GITOPS_RETRY_MIN_WAIT")
+	retry_max_wait := os.Getenv("GITOPS_RETRY_MAX_WAIT")
+	var immutable_instance_attributes []string
+	if env := os.Getenv("GITOPS_IMMUTABLE_INSTANCE_ATTRIBUTES"); env != "" {
+		for _, attribute := range strings.Split(env, ",") {
+			immutable_instance_attributes = append(immutable_instance_attributes, strings.TrimSpace(attribute))
+		}
+	}
+	default_labels := map[string]string{}
+	if env := os.Getenv("GITOPS_DEFAULT_LABELS"); env != "" {
+		for _, label := range strings.Split(env, ",") {
+			key, value, found := strings.Cut(label, "=")
+			if !found || strings.TrimSpace(key) == "" {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("default_labels"),
+					"Invalid Gitops API default_labels",
+					"The provider cannot create the gitops API client as the GITOPS_DEFAULT_LABELS environment variable must be a comma separated key=value list, got: "+label+".",
+				)
+				continue
+			}
+			default_labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
 
 	if !config.GitopsApiURI.IsNull() {
 		gitops_api_uri = config.GitopsApiURI.ValueString()
 	}
 
+	if !config.Environment.IsNull() {
+		environment = config.Environment.ValueString()
+	}
+
 	if !config.CachePath.IsNull() {
 		cache_path = config.CachePath.ValueString()
 	}
 
+	if !config.CacheMode.IsNull() {
+		cache_mode = config.CacheMode.ValueString()
+	}
+
+	if !config.AccessToken.IsNull() {
+		access_token = config.AccessToken.ValueString()
+	}
+
 	if !config.Username.IsNull() {
 		username = config.Username.ValueString()
 	}
@@ -324,6 +551,52 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 		grant_type = config.GrantType.ValueString()
 	}
 
+	// The client_credentials grant only needs client_id, client_secret and
+	// token_uri, its tokens are cached in memory without a cache_path
+	if cache_mode == "" {
+		cache_mode = cacheModeDisk
+		if grant_type == grantTypeClientCredentials && cache_path == "" {
+			cache_mode = cacheModeMemory
+		}
+	}
+
+	// An ID token configured either way replaces one from the environment
+	if !config.IdToken.IsNull() {
+		id_token = config.IdToken.ValueString()
+		id_token_file = ""
+	}
+
+	if !config.IdTokenFile.IsNull() {
+		id_token_file = config.IdTokenFile.ValueString()
+		id_token = ""
+	}
+
+	if !config.Debug.IsNull() {
+		debug = strconv.FormatBool(config.Debug.ValueBool())
+	}
+
+	if !config.MaxRetries.IsNull() {
+		max_retries = strconv.FormatInt(config.MaxRetries.ValueInt64(), 10)
+	}
+
+	if !config.RetryMinWait.IsNull() {
+		retry_min_wait = config.RetryMinWait.ValueString()
+	}
+
+	if !config.RetryMaxWait.IsNull() {
+		retry_max_wait = config.RetryMaxWait.ValueString()
+	}
+
+	if !config.ImmutableInstanceAttributes.IsNull() {
+		immutable_instance_attributes = nil
+		resp.Diagnostics.Append(config.ImmutableInstanceAttributes.ElementsAs(ctx, &immutable_instance_attributes, false)...)
+	}
+
+	if !config.DefaultLabels.IsNull() {
+		default_labels = map[string]string{}
+		resp.Diagnostics.Append(config.DefaultLabels.ElementsAs(ctx, &default_labels, false)...)
+	}
+
 	// If any of the expected configurations are missing, return
 	// errors with provider-specific guidance.
 
@@ -337,93 +610,175 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 		)
 	}
 
-	if cache_path == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("cache_path"),
-			"Missing Gitops API cache_path",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API cache_path. "+
-				"Set the cache_path value in the configuration or use the GITOPS_CACHEPATH environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
-	}
+	// The OAuth2 configuration is only needed without a pre-issued access token
+	if access_token == "" {
+		if !slices.Contains(cacheModes, cache_mode) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("cache_mode"),
+				"Invalid Gitops API cache_mode",
+				"The provider cannot create the gitops API client as cache_mode must be one of "+strings.Join(cacheModes, ", ")+", got: "+cache_mode+". "+
+					"Check the cache_mode value in the configuration or the GITOPS_CACHE_MODE environment variable.",
+			)
+		} else if cache_mode == cacheModeDisk && cache_path == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("cache_path"),
+				"Missing Gitops API cache_path",
+				"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API cache_path, "+
+					"which cache_mode disk requires. "+
+					"Set the cache_path value in the configuration or use the GITOPS_CACHEPATH environment variable, "+
+					"or choose another cache_mode. If either is already set, ensure the value is not empty.",
+			)
+		}
 
-	/*if username == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("username"),
-			"Missing Gitops API username",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API username. "+
-				"Set the username value in the configuration or use the GITOPS_USERNAME environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
-	}*/
+		if client_id == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("client_id"),
+				"Missing Gitops API client_id",
+				"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API client_id. "+
+					"Set the client_id value in the configuration or use the GITOPS_CLIENTID environment variable. "+
+					"If either is already set, ensure the value is not empty.",
+			)
+		}
 
-	/*if password == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("password"),
-			"Missing Gitops API password",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API password. "+
-				"Set the password value in the configuration or use the GITOPS_PASSWORD environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
-	}*/
+		if token_uri == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token_uri"),
+				"Missing Gitops API token_uri",
+				"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API token_uri. "+
+					"Set the token_uri value in the configuration or use the GITOPS_TOKENURI environment variable. "+
+					"If either is already set, ensure the value is not empty.",
+			)
+		}
 
-	if client_id == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("client_id"),
-			"Missing Gitops API client_id",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API client_id. "+
-				"Set the client_id value in the configuration or use the GITOPS_CLIENTID environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
+		if grant_type == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("grant_type"),
+				"Missing Gitops API grant_type",
+				"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API grant_type. "+
+					"Set the grant_type value in the configuration or use the GITOPS_GRANTTYPE environment variable. "+
+					"If either is already set, ensure the value is not empty.",
+			)
+		} else if !slices.Contains(grantTypes, grant_type) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("grant_type"),
+				"Invalid Gitops API grant_type",
+				"The provider cannot create the gitops API client as grant_type must be one of "+strings.Join(grantTypes, ", ")+", got: "+grant_type+". "+
+					"Check the grant_type value in the configuration or the GITOPS_GRANTTYPE environment variable.",
+			)
+		}
+
+		// The remaining settings depend on the grant type
+		requiredByGrant := []struct {
+			attribute string
+			value     string
+			env       string
+			grants    []string
+		}{
+			{"username", username, "GITOPS_USERNAME", []string{grantTypePassword}},
+			{"password", password, "GITOPS_PASSWORD", []string{grantTypePassword}},
+			{"client_secret", client_secret, "GITOPS_CLIENTSECRET", []string{grantTypeClientCredentials}},
+			{"auth_uri", auth_uri, "GITOPS_AUTHURI", []string{grantTypeAuthCode, grantTypeDeviceCode}},
+			{"redirect_uri", redirect_uri, "GITOPS_REDIRECTURI", []string{grantTypeAuthCode}},
+			{"authz_listener_socket", authz_listener_socket, "GITOPS_AUTHZLISTENERSOCKET", []string{grantTypeAuthCode}},
+		}
+		for _, required := range requiredByGrant {
+			if required.value == "" && slices.Contains(required.grants, grant_type) {
+				resp.Diagnostics.AddAttributeError(
+					path.Root(required.attribute),
+					"Missing Gitops API "+required.attribute,
+					"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API "+required.attribute+", "+
+						"which grant_type "+grant_type+" requires. "+
+						"Set the "+required.attribute+" value in the configuration or use the "+required.env+" environment variable. "+
+						"If either is already set, ensure the value is not empty.",
+				)
+			}
+		}
+
+		if slices.Contains(idTokenGrantTypes, grant_type) && id_token == "" && id_token_file == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("id_token_file"),
+				"Missing Gitops API id_token_file",
+				"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API id_token and id_token_file, "+
+					"one of which grant_type "+grant_type+" requires. "+
+					"Set the id_token_file or id_token value in the configuration or use the GITOPS_ID_TOKEN_FILE or GITOPS_ID_TOKEN environment variable. "+
+					"If either is already set, ensure the value is not empty.",
+			)
+		}
 	}
 
-	/*if client_secret == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("client_secret"),
-			"Missing Gitops API client_secret",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API client_secret. "+
-				"Set the client_secret value in the configuration or use the GITOPS_CLIENTSECRET environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
-	}*/
+	debugEnabled := false
+	if debug != "" {
+		enabled, err := strconv.ParseBool(debug)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("debug"),
+				"Invalid Gitops API debug",
+				"The provider cannot create the gitops API client as debug must be true or false, got: "+debug+". "+
+					"Check the debug value in the configuration or the GITOPS_DEBUG environment variable.",
+			)
+		}
+		debugEnabled = enabled
+	}
 
-	if token_uri == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("token_uri"),
-			"Missing Gitops API token_uri",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API token_uri. "+
-				"Set the token_uri value in the configuration or use the GITOPS_TOKENURI environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
+	retryPolicy := defaultRetryPolicy()
+
+	if max_retries != "" {
+		retries, err := strconv.Atoi(max_retries)
+		if err != nil || retries < 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("max_retries"),
+				"Invalid Gitops API max_retries",
+				"The provider cannot create the gitops API client as max_retries must be a non-negative number, got: "+max_retries+". "+
+					"Check the max_retries value in the configuration or the GITOPS_MAX_RETRIES environment variable.",
+			)
+		}
+		retryPolicy.MaxRetries = retries
 	}
 
-	if auth_uri == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("auth_uri"),
-			"Missing Gitops API auth_uri",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API auth_uri. "+
-				"Set the auth_uri value in the configuration or use the GITOPS_AUTHURI environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
+	if retry_min_wait != "" {
+		wait, err := time.ParseDuration(retry_min_wait)
+		if err != nil || wait <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("retry_min_wait"),
+				"Invalid Gitops API retry_min_wait",
+				"The provider cannot create the gitops API client as retry_min_wait must be a positive duration like \"1s\", got: "+retry_min_wait+". "+
+					"Check the retry_min_wait value in the configuration or the GITOPS_RETRY_MIN_WAIT environment variable.",
+			)
+		}
+		retryPolicy.MinWait = wait
 	}
 
-	if jwks_uri == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("jwks_uri"),
-			"Missing Gitops API jwks_uri",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API jwks_uri. "+
-				"Set the jwks_uri value in the configuration or use the GITOPS_JWKSURI environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
+	if retry_max_wait != "" {
+		wait, err := time.ParseDuration(retry_max_wait)
+		if err != nil || wait <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("retry_max_wait"),
+				"Invalid Gitops API retry_max_wait",
+				"The provider cannot create the gitops API client as retry_max_wait must be a positive duration like \"30s\", got: "+retry_max_wait+". "+
+					"Check the retry_max_wait value in the configuration or the GITOPS_RETRY_MAX_WAIT environment variable.",
+			)
+		}
+		retryPolicy.MaxWait = wait
+	}
+
+	for _, attribute := range immutable_instance_attributes {
+		if !slices.Contains(mutableInstanceAttributes, attribute) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("immutable_instance_attributes"),
+				"Invalid Gitops API immutable_instance_attributes",
+				"The provider cannot create the gitops API client as immutable_instance_attributes only supports "+
+					strings.Join(mutableInstanceAttributes, " and ")+", got: "+attribute+". "+
+					"Check the immutable_instance_attributes value in the configuration or the GITOPS_IMMUTABLE_INSTANCE_ATTRIBUTES environment variable.",
+			)
+		}
 	}
 
-	if grant_type == "" {
+	if !resp.Diagnostics.HasError() && retryPolicy.MinWait > retryPolicy.MaxWait {
 		resp.Diagnostics.AddAttributeError(
-			path.Root("grant_type"),
-			"Missing Gitops API grant_type",
-			"The provider cannot create the gitops API client as there is a missing or empty value for the gitops API grant_type. "+
-				"Set the grant_type value in the configuration or use the GITOPS_GRANTTYPE environment variable. "+
-				"If either is already set, ensure the value is not empty.",
+			path.Root("retry_min_wait"),
+			"Invalid Gitops API retry_min_wait",
+			"The provider cannot create the gitops API client as retry_min_wait ("+retryPolicy.MinWait.String()+") "+
+				"is longer than retry_max_wait ("+retryPolicy.MaxWait.String()+").",
 		)
 	}
 
@@ -432,6 +787,9 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 	}
 
 	ctx = tflog.SetField(ctx, "gitops_host", gitops_api_uri)
+	if environment != "" {
+		ctx = tflog.SetField(ctx, "gitops_environment", environment)
+	}
 	ctx = tflog.SetField(ctx, "gitops_username", username)
 	ctx = tflog.SetField(ctx, "gitops_clientid", client_id)
 	ctx = tflog.SetField(ctx, "gitops_tokenuri", token_uri)
@@ -465,34 +823,104 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 		)
 		return
 	}
-	// get access-token according to OAuth2 settings (grant_type, client_id, ...)
-	var isTokenValid bool = false
-	err = client.GetTokenFromCache("access")
-	if err == nil {
-		_, err = client.ParseToken(client.AccessToken)
-		if err == nil {
-			isTokenValid = true
+	apiClient := newGitopsApiClient(client, retryPolicy)
+	apiClient.immutableInstanceAttributes = immutable_instance_attributes
+	apiClient.defaultLabels = default_labels
+	apiClient.idToken = id_token
+	apiClient.idTokenFile = id_token_file
+	apiClient.environment = environment
+	identity := tokenCacheIdentity{
+		Issuer:      token_uri,
+		ClientId:    client_id,
+		Audience:    gitops_api_uri,
+		Environment: environment,
+		Scopes:      scopes,
+		GrantType:   grant_type,
+	}
+	// Only the password grant authenticates as username
+	if grant_type == grantTypePassword {
+		identity.Username = username
+	}
+	apiClient.tokenCache = newTokenCache(cache_mode, cache_path, identity)
+	if cache_path != "" {
+		apiClient.ordersPath = filepath.Join(cache_path, "orders")
+	}
+	ctx = apiClient.maskCredentials(ctx, access_token)
+	// gitopsclient debug mode prints unredacted responses to stdout, so it
+	// stays off and the provider traces the requests itself
+	if debugEnabled {
+		apiClient.enableTracing()
+	}
+
+	if access_token != "" {
+		// Pre-issued access tokens cannot be renewed, they have to last
+		// for the whole run.
+		expiresAt, ok, err := accessTokenExpiry(access_token)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("access_token"),
+				"Invalid Gitops API access_token",
+				"The provider cannot create the gitops API client as the access_token cannot be parsed: "+err.Error()+". "+
+					"Check the access_token value in the configuration or the GITOPS_TOKEN environment variable.",
+			)
+			return
 		}
+		if !ok {
+			tflog.Warn(ctx, "Gitops API access_token has no expiry, it may expire while Terraform runs")
+		} else if validity := time.Until(expiresAt); validity < minStaticAccessTokenValidity {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("access_token"),
+				"Expiring Gitops API access_token",
+				"The provider cannot create the gitops API client as the access_token expires at "+expiresAt.Format(time.RFC3339)+", "+
+					"likely before Terraform finishes. Pre-issued access tokens cannot be renewed, provide one valid for at least "+
+					minStaticAccessTokenValidity.String()+" in the access_token value in the configuration or the GITOPS_TOKEN environment variable, "+
+					"or remove it to let the provider obtain access tokens with the OAuth2 configuration.",
+			)
+			return
+		}
+		apiClient.AccessToken = access_token
+		apiClient.staticAccessToken = true
+	} else if _, err = apiClient.accessToken(ctx); err != nil {
+		// Uses the cached access-token, or gets one according to the OAuth2
+		// settings (grant_type, client_id, ...)
+		resp.Diagnostics.AddError(
+			"Unable to Create Gitops API access-token",
+			"An unexpected error occurred when creating the gitops API access-token. "+
+				"If the error is not clear, please contact the provider developers.\n\n"+
+				"Gitops Client Error: "+err.Error(),
+		)
+		return
 	}
-	if !isTokenValid {
-		err = client.GetToken()
+	tflog.Debug(ctx, "Obtained gitops access token", accessTokenMetadata(apiClient.AccessToken))
+
+	// Catch aliases pointing to the Gitops API of another environment
+	if environment != "" {
+		info, err := apiClient.GetApiInfo(ctx)
 		if err != nil {
 			resp.Diagnostics.AddError(
-				"Unable to Create Gitops API access-token",
-				"An unexpected error occurred when creating the gitops API access-token. "+
-					"If the error is not clear, please contact the provider developers.\n\n"+
+				"Unable to Verify Gitops API environment",
+				"An unexpected error occurred when reading the environment reported by the gitops API at "+gitops_api_uri+". "+
+					"The provider verifies it as environment is set.\n\n"+
 					"Gitops Client Error: "+err.Error(),
 			)
 			return
 		}
+		if info.Environment != environment {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("environment"),
+				"Mismatching Gitops API environment",
+				"The provider cannot use the gitops API at "+gitops_api_uri+" as it reports environment "+strconv.Quote(info.Environment)+", "+
+					"but the provider is configured for environment "+strconv.Quote(environment)+". "+
+					"Check the gitops_api_uri and environment values in the configuration or the GITOPS_HOST and GITOPS_ENVIRONMENT environment variables.",
+			)
+			return
+		}
 	}
-	tflog.Debug(ctx, "Gitops Client acces_token: "+client.AccessToken)
-	tflog.Debug(ctx, "Gitops Client refresh_token: "+client.RefreshToken)
 
 	// Make the gitops client available during DataSource and Resource
 	// type Configure methods.
-	resp.DataSourceData = client
-	resp.ResourceData = client
+	resp.DataSourceData = apiClient
+	resp.ResourceData = apiClient
 
 	tflog.Info(ctx, "Configured Gitops client", map[string]any{"success": true})
 }
@@ -501,6 +929,7 @@ func (p *gitopsProvider) Configure(ctx context.Context, req provider.ConfigureRe
 func (p *gitopsProvider) Resources(_ context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewGitopsInstanceResource,
+		NewGitopsManifestResource,
 	}
 }
 
@@ -508,5 +937,18 @@ func (p *gitopsProvider) Resources(_ context.Context) []func() resource.Resource
 func (p *gitopsProvider) DataSources(_ context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewGitopsDataSource,
+		NewGitopsInstanceDataSource,
+		NewGitopsInstanceHistoryDataSource,
+		NewGitopsServicesDataSource,
+		NewGitopsServiceDataSource,
+	}
+}
+
+// Functions defines the functions implemented in the provider.
+func (p *gitopsProvider) Functions(_ context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewVersionMatchesFunction,
+		NewResolveVersionFunction,
+		NewParseInstanceIdFunction,
 	}
 }
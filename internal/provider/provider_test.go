@@ -0,0 +1,554 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// testAccProtoV6ProviderFactories are used to instantiate a provider during
+// acceptance testing. The factory function will be invoked for every Terraform
+// CLI command executed to create a provider server to which the CLI can
+// reattach.
+var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"gitops": providerserver.NewProtocol6WithError(New("test")()),
+}
+
+func TestMain(m *testing.M) {
+	// The fake Gitops API rolls instances out right away, there is no
+	// need to wait between stage polls.
+	stageWaitMinInterval = 10 * time.Millisecond
+	stageWaitMaxInterval = 50 * time.Millisecond
+	os.Exit(m.Run())
+}
+
+// testAccProviderConfig configures the provider for the fake Gitops API
+// api, caching tokens in cachePath.
+func testAccProviderConfig(api *fakeGitopsApi, cachePath string) string {
+	return fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  cache_path     = %[2]q
+  grant_type     = "password"
+  client_id      = %[3]q
+  username       = %[4]q
+  password       = %[5]q
+  token_uri      = "%[1]s/token"
+  auth_uri       = "%[1]s/auth"
+  jwks_uri       = "%[1]s/certs"
+  max_retries    = 2
+  retry_min_wait = "10ms"
+  retry_max_wait = "50ms"
+}
+`, api.URL, cachePath, fakeClientId, fakeUsername, fakePassword)
+}
+
+// testAccProviderConfigWith is testAccProviderConfig with the additional
+// provider attributes in attributes.
+func testAccProviderConfigWith(api *fakeGitopsApi, cachePath string, attributes string) string {
+	return strings.Replace(testAccProviderConfig(api, cachePath), "provider \"gitops\" {", "provider \"gitops\" {\n"+attributes, 1)
+}
+
+// testAccTokenCache returns the disk token cache in cachePath of the
+// provider configured by testAccProviderConfig.
+func testAccTokenCache(api *fakeGitopsApi, cachePath string) tokenCache {
+	return newTokenCache(cacheModeDisk, cachePath, tokenCacheIdentity{
+		Issuer:    api.URL + "/token",
+		ClientId:  fakeClientId,
+		Audience:  api.URL,
+		GrantType: grantTypePassword,
+		Username:  fakeUsername,
+	})
+}
+
+func TestAccProviderGetsTokenWithPasswordGrant(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	cachePath := t.TempDir()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, cachePath) + `
+data "gitops_plans" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.gitops_plans.test", "plans.#", "0"),
+					func(_ *terraform.State) error {
+						cached, err := testAccTokenCache(api, cachePath).load()
+						if err != nil {
+							return err
+						}
+						if cached.AccessToken == "" || cached.RefreshToken == "" {
+							return fmt.Errorf("got cached tokens %+v, want an access and a refresh token", cached)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccProviderReusesCachedToken(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	cachePath := t.TempDir()
+	err := testAccTokenCache(api, cachePath).store(cachedTokens{AccessToken: testAccessToken(t, time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, cachePath) + `
+data "gitops_plans" "test" {}
+`,
+				Check: func(_ *terraform.State) error {
+					if api.issuedTokens() != 0 {
+						return fmt.Errorf("got %d tokens issued, want the cached token to be used", api.issuedTokens())
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccProviderReplacesExpiredToken(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	cachePath := t.TempDir()
+	err := testAccTokenCache(api, cachePath).store(cachedTokens{AccessToken: testAccessToken(t, -time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, cachePath) + `
+data "gitops_plans" "test" {}
+`,
+				Check: func(_ *terraform.State) error {
+					if api.issuedTokens() == 0 {
+						return fmt.Errorf("expired cached token was not replaced")
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+// testAccCacheModeProviderConfig configures the provider for the fake
+// Gitops API api, caching tokens according to cacheMode.
+func testAccCacheModeProviderConfig(api *fakeGitopsApi, cacheMode string) string {
+	return fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  cache_mode     = %[2]q
+  grant_type     = "password"
+  client_id      = %[3]q
+  username       = %[4]q
+  password       = %[5]q
+  token_uri      = "%[1]s/token"
+}
+
+data "gitops_plans" "test" {}
+`, api.URL, cacheMode, fakeClientId, fakeUsername, fakePassword)
+}
+
+// The acceptance tests run the provider in process, where every Terraform
+// command of the test steps configures the provider anew, like the provider
+// configurations of a single Terraform run.
+
+func TestAccProviderSharesTokensInMemory(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCacheModeProviderConfig(api, cacheModeMemory),
+			},
+			{
+				Config: testAccCacheModeProviderConfig(api, cacheModeMemory),
+				Check: func(_ *terraform.State) error {
+					if api.issuedTokens() != 1 {
+						return fmt.Errorf("got %d tokens issued, want 1 shared by all provider configurations", api.issuedTokens())
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccProviderWithoutTokenCache(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCacheModeProviderConfig(api, cacheModeNone),
+			},
+			{
+				Config: testAccCacheModeProviderConfig(api, cacheModeNone),
+				Check: func(_ *terraform.State) error {
+					if api.issuedTokens() < 2 {
+						return fmt.Errorf("got %d tokens issued, want one per provider configuration", api.issuedTokens())
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccProviderRejectsInvalidCacheMode(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCacheModeProviderConfig(api, "redis"),
+				ExpectError: regexp.MustCompile(`cache_mode`),
+			},
+		},
+	})
+}
+
+func TestAccProviderRejectsInvalidCredentials(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  cache_path     = %[2]q
+  grant_type     = "password"
+  client_id      = %[3]q
+  username       = %[4]q
+  password       = "wrong"
+  token_uri      = "%[1]s/token"
+  auth_uri       = "%[1]s/auth"
+  jwks_uri       = "%[1]s/certs"
+}
+
+data "gitops_plans" "test" {}
+`, api.URL, t.TempDir(), fakeClientId, fakeUsername),
+				ExpectError: regexp.MustCompile(`Unable to Create Gitops API access-token`),
+			},
+		},
+	})
+}
+
+// testAccClientCredentialsProviderConfig configures the provider for the
+// fake Gitops API api with the client credentials grant.
+func testAccClientCredentialsProviderConfig(api *fakeGitopsApi, cachePath string, clientSecret string) string {
+	return fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  cache_path     = %[2]q
+  grant_type     = "client_credentials"
+  client_id      = %[3]q
+  client_secret  = %[4]q
+  token_uri      = "%[1]s/token"
+}
+`, api.URL, cachePath, fakeClientId, clientSecret)
+}
+
+func TestAccProviderGetsTokenWithClientCredentialsGrant(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	cachePath := t.TempDir()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccClientCredentialsProviderConfig(api, cachePath, fakeClientSecret) + testAccInstanceConfig("test-instance", 1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "stage", "deployed"),
+					func(_ *terraform.State) error {
+						// Later runs use the cached token without a jwks_uri
+						if api.issuedTokens() != 1 {
+							return fmt.Errorf("got %d tokens issued, want 1", api.issuedTokens())
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccProviderClientCredentialsGrantWithoutCachePath(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	config := fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  grant_type     = "client_credentials"
+  client_id      = %[2]q
+  client_secret  = %[3]q
+  token_uri      = "%[1]s/token"
+}
+`, api.URL, fakeClientId, fakeClientSecret)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config: config + testAccInstanceConfig("test-instance", 1),
+				Check:  resource.TestCheckResourceAttr("gitops_instance.test", "stage", "deployed"),
+			},
+		},
+	})
+}
+
+func TestAccProviderRejectsInvalidClientCredentials(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccClientCredentialsProviderConfig(api, t.TempDir(), "wrong") + `
+data "gitops_plans" "test" {}
+`,
+				ExpectError: regexp.MustCompile(`Invalid client or Invalid client credentials`),
+			},
+		},
+	})
+}
+
+func TestAccProviderRequiresClientSecretForClientCredentialsGrant(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccClientCredentialsProviderConfig(api, t.TempDir(), "") + `
+data "gitops_plans" "test" {}
+`,
+				ExpectError: regexp.MustCompile(`Missing Gitops API client_secret`),
+			},
+		},
+	})
+}
+
+func TestAccProviderExchangesIdTokenFile(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	idTokenFile := filepath.Join(t.TempDir(), "id_token")
+	err := os.WriteFile(idTokenFile, []byte(testAccessToken(t, time.Hour)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  cache_path     = %[2]q
+  grant_type     = "token_exchange"
+  client_id      = %[3]q
+  id_token_file  = %[4]q
+  token_uri      = "%[1]s/token"
+}
+
+data "gitops_plans" "test" {}
+`, api.URL, t.TempDir(), fakeClientId, idTokenFile),
+				Check: resource.TestCheckResourceAttr("data.gitops_plans.test", "plans.#", "0"),
+			},
+		},
+	})
+}
+
+func TestAccProviderExchangesIdTokenWithJwtBearerGrant(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  cache_path     = %[2]q
+  grant_type     = "jwt_bearer"
+  client_id      = %[3]q
+  id_token       = %[4]q
+  token_uri      = "%[1]s/token"
+}
+
+data "gitops_plans" "test" {}
+`, api.URL, t.TempDir(), fakeClientId, testAccessToken(t, time.Hour)),
+				Check: resource.TestCheckResourceAttr("data.gitops_plans.test", "plans.#", "0"),
+			},
+		},
+	})
+}
+
+func TestAccProviderRequiresIdTokenForTokenExchange(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	t.Setenv("GITOPS_ID_TOKEN", "")
+	t.Setenv("GITOPS_ID_TOKEN_FILE", "")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  cache_path     = %[2]q
+  grant_type     = "token_exchange"
+  client_id      = %[3]q
+  token_uri      = "%[1]s/token"
+}
+
+data "gitops_plans" "test" {}
+`, api.URL, t.TempDir(), fakeClientId),
+				ExpectError: regexp.MustCompile(`Missing Gitops API id_token_file`),
+			},
+		},
+	})
+}
+
+func TestAccProviderUsesPreIssuedAccessToken(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  access_token   = %[2]q
+}
+`, api.URL, testAccessToken(t, time.Hour)) + testAccInstanceConfig("test-instance", 1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("gitops_instance.test", "stage", "deployed"),
+					func(_ *terraform.State) error {
+						if api.issuedTokens() != 0 {
+							return fmt.Errorf("got %d tokens issued, want the pre-issued token to be used", api.issuedTokens())
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccProviderRejectsExpiringAccessToken(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "gitops" {
+  gitops_api_uri = %[1]q
+  access_token   = %[2]q
+}
+
+data "gitops_plans" "test" {}
+`, api.URL, testAccessToken(t, 5*time.Minute)),
+				ExpectError: regexp.MustCompile(`Expiring Gitops API access_token`),
+			},
+		},
+	})
+}
+
+func TestAccProviderDebug(t *testing.T) {
+	api := newFakeGitopsApi(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfigWith(api, t.TempDir(), "debug = true") + `
+data "gitops_plans" "test" {}
+`,
+				Check: resource.TestCheckResourceAttr("data.gitops_plans.test", "plans.#", "0"),
+			},
+		},
+	})
+}
+
+func TestAccProviderRejectsInvalidDebugEnv(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	t.Setenv("GITOPS_DEBUG", "sometimes")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(api, t.TempDir()) + `
+data "gitops_plans" "test" {}
+`,
+				ExpectError: regexp.MustCompile(`Invalid Gitops API debug`),
+			},
+		},
+	})
+}
+
+func TestAccProviderEnvironment(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.environment = "prod"
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfigWith(api, t.TempDir(), `environment = "prod"`) + testAccInstanceConfig("test-instance", 1),
+				Check: resource.TestCheckResourceAttrWith("gitops_instance.test", "instance_id", func(value string) error {
+					if environment := api.orderedEnvironment(value); environment != "prod" {
+						return fmt.Errorf("got instance ordered for environment %q, want prod", environment)
+					}
+					return nil
+				}),
+			},
+		},
+	})
+}
+
+func TestAccProviderRejectsMismatchingEnvironment(t *testing.T) {
+	api := newFakeGitopsApi(t)
+	api.environment = "dev"
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckInstanceDestroyed(api),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccProviderConfigWith(api, t.TempDir(), `environment = "prod"`) + testAccInstanceConfig("test-instance", 1),
+				ExpectError: regexp.MustCompile(`Mismatching Gitops API environment`),
+			},
+		},
+	})
+}
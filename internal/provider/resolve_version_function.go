@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ function.Function = &resolveVersionFunction{}
+
+// NewResolveVersionFunction is a helper function to simplify the provider implementation.
+func NewResolveVersionFunction() function.Function {
+	return &resolveVersionFunction{}
+}
+
+// resolveVersionFunction is the function implementation.
+type resolveVersionFunction struct{}
+
+// Metadata returns the function name.
+func (f *resolveVersionFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "resolve_version"
+}
+
+// Definition defines the parameters and return type of the function.
+func (f *resolveVersionFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Resolves a Gitops version constraint to the highest matching version",
+		Description: "Returns the version of available_versions with the highest semantic version precedence that satisfies the constraint, " +
+			"the same way the Gitops API resolves wildcard versions. Fails if no version satisfies the constraint.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "constraint",
+				Description: "Version constraint, e.g. \"3.2.*\"",
+			},
+			function.ListParameter{
+				Name:        "available_versions",
+				Description: "Semantic versions to choose from",
+				ElementType: types.StringType,
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+// Run resolves the constraint.
+func (f *resolveVersionFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var constraint string
+	var availableVersions []string
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &constraint, &availableVersions))
+	if resp.Error != nil {
+		return
+	}
+
+	parsedConstraint, err := parseVersionConstraint(constraint)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	resolved, ok, err := resolveVersion(parsedConstraint, availableVersions)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+	if !ok {
+		resp.Error = function.NewFuncError("no available version satisfies the constraint " + constraint)
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, resolved))
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+// Bounds of the instance attributes accepted by the Gitops API.
+const (
+	minReplicaCount       = 1
+	maxReplicaCount       = 100
+	maxInstanceNameLength = 128
+)
+
+var (
+	// emailRegexp loosely matches e-mail addresses, the Gitops API only
+	// requires a local part and a domain.
+	emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+	// instanceNameRegexp matches names starting with a letter or digit,
+	// followed by letters, digits, spaces, dots, dashes and underscores.
+	instanceNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ._-]*$`)
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ validator.String = versionConstraintValidator{}
+	_ validator.String = jsonObjectValidator{}
+)
+
+// versionConstraintValidator validates that a string is a semantic
+// version or a wildcard version as understood by the Gitops API.
+type versionConstraintValidator struct{}
+
+// Description describes the validation in plain text formatting.
+func (v versionConstraintValidator) Description(_ context.Context) string {
+	return `value must be a semantic version like "3.2.1" or a wildcard version like "3.2.*"`
+}
+
+// MarkdownDescription describes the validation in Markdown formatting.
+func (v versionConstraintValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+// ValidateString performs the validation.
+func (v versionConstraintValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if _, err := parseVersionConstraint(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Version",
+			"Attribute "+req.Path.String()+" "+v.Description(ctx)+", got: "+req.ConfigValue.ValueString(),
+		)
+	}
+}
+
+// jsonObjectValidator validates that a string is a JSON-encoded object.
+type jsonObjectValidator struct{}
+
+// Description describes the validation in plain text formatting.
+func (v jsonObjectValidator) Description(_ context.Context) string {
+	return "value must be a JSON-encoded object, e.g. the result of jsonencode"
+}
+
+// MarkdownDescription describes the validation in Markdown formatting.
+func (v jsonObjectValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+// ValidateString performs the validation.
+func (v jsonObjectValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal([]byte(req.ConfigValue.ValueString()), &object); err != nil || object == nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid JSON Object",
+			"Attribute "+req.Path.String()+" "+v.Description(ctx)+", got: "+req.ConfigValue.ValueString(),
+		)
+	}
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// semverRegexp matches semantic versions like "3.2.1" or "3.2.1-rc.1+build.5".
+var semverRegexp = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?$`)
+
+// semVersion is a parsed semantic version.
+type semVersion struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// parseVersion parses a semantic version. Build metadata is dropped.
+func parseVersion(version string) (semVersion, error) {
+	match := semverRegexp.FindStringSubmatch(version)
+	if match == nil {
+		return semVersion{}, fmt.Errorf("%q is not a semantic version like \"3.2.1\"", version)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	return semVersion{
+		Major:      major,
+		Minor:      minor,
+		Patch:      patch,
+		Prerelease: match[4],
+	}, nil
+}
+
+// versionConstraint is a version as ordered from the Gitops API: either
+// an exact semantic version like "3.2.1" or a version prefix ending in a
+// wildcard like "3.2.*", "3.*" or "*".
+type versionConstraint struct {
+	// exact is set for constraints naming a single version
+	exact *semVersion
+	// prefix holds the fixed leading components of wildcard constraints
+	prefix []int
+}
+
+// parseVersionConstraint parses a version constraint.
+func parseVersionConstraint(constraint string) (versionConstraint, error) {
+	if version, err := parseVersion(constraint); err == nil {
+		return versionConstraint{exact: &version}, nil
+	}
+
+	invalid := fmt.Errorf("%q is neither a semantic version like \"3.2.1\" nor a wildcard version like \"3.2.*\"", constraint)
+	components := strings.Split(constraint, ".")
+	if len(components) > 3 || components[len(components)-1] != "*" {
+		return versionConstraint{}, invalid
+	}
+	var prefix []int
+	for _, component := range components[:len(components)-1] {
+		number, err := strconv.Atoi(component)
+		if err != nil || number < 0 || strconv.Itoa(number) != component {
+			return versionConstraint{}, invalid
+		}
+		prefix = append(prefix, number)
+	}
+	return versionConstraint{prefix: prefix}, nil
+}
+
+// Check reports whether version satisfies the constraint. Wildcards only
+// match releases, pre-releases have to be ordered by their exact version.
+func (c versionConstraint) Check(version semVersion) bool {
+	if c.exact != nil {
+		return *c.exact == version
+	}
+	if version.Prerelease != "" {
+		return false
+	}
+	components := []int{version.Major, version.Minor, version.Patch}
+	for i, number := range c.prefix {
+		if components[i] != number {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether both constraints are satisfied by the same
+// versions, e.g. "3.2.1" and "3.2.1+build.5".
+func (c versionConstraint) Equal(other versionConstraint) bool {
+	if c.exact != nil || other.exact != nil {
+		return c.exact != nil && other.exact != nil && *c.exact == *other.exact
+	}
+	return slices.Equal(c.prefix, other.prefix)
+}
+
+// equalVersions reports whether a and b are the same semantic version,
+// ignoring build metadata.
+func equalVersions(a string, b string) bool {
+	aVersion, aErr := parseVersion(a)
+	bVersion, bErr := parseVersion(b)
+	return aErr == nil && bErr == nil && aVersion == bVersion
+}
+
+// compareVersions returns -1, 0 or 1 if a has a lower, the same or a higher
+// precedence than b according to the semantic versioning specification.
+func compareVersions(a semVersion, b semVersion) int {
+	if c := cmp.Or(cmp.Compare(a.Major, b.Major), cmp.Compare(a.Minor, b.Minor), cmp.Compare(a.Patch, b.Patch)); c != 0 {
+		return c
+	}
+
+	// A release has a higher precedence than its pre-releases
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	}
+
+	aIdentifiers := strings.Split(a.Prerelease, ".")
+	bIdentifiers := strings.Split(b.Prerelease, ".")
+	for i := 0; i < len(aIdentifiers) && i < len(bIdentifiers); i++ {
+		aNumber, aErr := strconv.Atoi(aIdentifiers[i])
+		bNumber, bErr := strconv.Atoi(bIdentifiers[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := cmp.Compare(aNumber, bNumber); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			// Numeric identifiers have a lower precedence
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(aIdentifiers[i], bIdentifiers[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(aIdentifiers), len(bIdentifiers))
+}
+
+// resolveVersion returns the version of versions with the highest precedence
+// satisfying constraint.
+func resolveVersion(constraint versionConstraint, versions []string) (string, bool, error) {
+	var resolved string
+	var resolvedVersion semVersion
+	for _, candidate := range versions {
+		version, err := parseVersion(candidate)
+		if err != nil {
+			return "", false, err
+		}
+		if !constraint.Check(version) {
+			continue
+		}
+		if resolved == "" || compareVersions(version, resolvedVersion) > 0 {
+			resolved, resolvedVersion = candidate, version
+		}
+	}
+	return resolved, resolved != "", nil
+}
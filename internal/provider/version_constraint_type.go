@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ basetypes.StringTypable                    = versionConstraintType{}
+	_ basetypes.StringValuableWithSemanticEquals = versionConstraintValue{}
+)
+
+// versionConstraintType is a string attribute type holding a version
+// constraint, see parseVersionConstraint. Constraints satisfied by the
+// same versions, e.g. "3.2.1" and "3.2.1+build.5", are semantically equal.
+type versionConstraintType struct {
+	basetypes.StringType
+}
+
+// String returns a human readable string of the type name.
+func (t versionConstraintType) String() string {
+	return "versionConstraintType"
+}
+
+// ValueType returns the Value type.
+func (t versionConstraintType) ValueType(_ context.Context) attr.Value {
+	return versionConstraintValue{}
+}
+
+// Equal returns true if the given type is equivalent.
+func (t versionConstraintType) Equal(o attr.Type) bool {
+	other, ok := o.(versionConstraintType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+// ValueFromString returns a StringValuable type given a StringValue.
+func (t versionConstraintType) ValueFromString(_ context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return versionConstraintValue{StringValue: in}, nil
+}
+
+// ValueFromTerraform returns a Value given a tftypes.Value.
+func (t versionConstraintType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+	return versionConstraintValue{StringValue: stringValue}, nil
+}
+
+// versionConstraintValue is a value of versionConstraintType.
+type versionConstraintValue struct {
+	basetypes.StringValue
+}
+
+// newVersionConstraintValue returns a known value holding constraint.
+func newVersionConstraintValue(constraint string) versionConstraintValue {
+	return versionConstraintValue{StringValue: basetypes.NewStringValue(constraint)}
+}
+
+// Type returns the type of the value.
+func (v versionConstraintValue) Type(_ context.Context) attr.Type {
+	return versionConstraintType{}
+}
+
+// Equal returns true if the given value is equivalent, including its
+// formatting.
+func (v versionConstraintValue) Equal(o attr.Value) bool {
+	other, ok := o.(versionConstraintValue)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+// StringSemanticEquals returns true if both constraints are satisfied by
+// the same versions, so that the Gitops API may normalize the constraint
+// without causing a diff.
+func (v versionConstraintValue) StringSemanticEquals(_ context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	newValue, ok := newValuable.(versionConstraintValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T, got: %T. Please report this issue to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	current, err := parseVersionConstraint(v.ValueString())
+	if err != nil {
+		return false, diags
+	}
+	updated, err := parseVersionConstraint(newValue.ValueString())
+	if err != nil {
+		return false, diags
+	}
+	return current.Equal(updated), diags
+}
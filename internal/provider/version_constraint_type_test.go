@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVersionConstraintSemanticEquals(t *testing.T) {
+	for _, test := range []struct {
+		current string
+		updated string
+		want    bool
+	}{
+		{"3.2.1", "3.2.1", true},
+		{"3.2.1", "3.2.1+build.5", true},
+		{"3.2.*", "3.2.*", true},
+		{"3.2.1", "3.2.2", false},
+		{"3.2.*", "3.2.1", false},
+		{"3.2.*", "3.*", false},
+		{"3.2.1-rc.1", "3.2.1", false},
+		{"3.2.1", "latest", false},
+	} {
+		got, diags := newVersionConstraintValue(test.current).StringSemanticEquals(context.Background(), newVersionConstraintValue(test.updated))
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		if got != test.want {
+			t.Errorf("%q and %q: got semantically equal %t, want %t", test.current, test.updated, got, test.want)
+		}
+	}
+}
+
+func TestInstanceVersion(t *testing.T) {
+	for _, test := range []struct {
+		current  versionConstraintValue
+		reported string
+		want     string
+	}{
+		// Imported instances adopt the reported version
+		{versionConstraintValue{}, "3.2.1", "3.2.1"},
+		{newVersionConstraintValue("3.2.*"), "3.2.*", "3.2.*"},
+		{newVersionConstraintValue("3.2.*"), "3.2.5", "3.2.*"},
+		{newVersionConstraintValue("3.2.1"), "3.2.1+build.5", "3.2.1"},
+		// Versions not satisfying the constraint are drift
+		{newVersionConstraintValue("3.2.*"), "3.3.0", "3.3.0"},
+		{newVersionConstraintValue("3.2.*"), "3.*", "3.*"},
+	} {
+		if got := instanceVersion(test.current, test.reported); got.ValueString() != test.want {
+			t.Errorf("%s reported as %q: got %q, want %q", test.current, test.reported, got.ValueString(), test.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ function.Function = &versionMatchesFunction{}
+
+// NewVersionMatchesFunction is a helper function to simplify the provider implementation.
+func NewVersionMatchesFunction() function.Function {
+	return &versionMatchesFunction{}
+}
+
+// versionMatchesFunction is the function implementation.
+type versionMatchesFunction struct{}
+
+// Metadata returns the function name.
+func (f *versionMatchesFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "version_matches"
+}
+
+// Definition defines the parameters and return type of the function.
+func (f *versionMatchesFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Checks whether a version satisfies a Gitops version constraint",
+		Description: "Returns true if the semantic version satisfies the constraint. Constraints are either exact semantic versions " +
+			"like \"3.2.1\" or wildcard versions like \"3.2.*\", \"3.*\" or \"*\". Wildcards do not match pre-release versions.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "constraint",
+				Description: "Version constraint, e.g. \"3.2.*\"",
+			},
+			function.StringParameter{
+				Name:        "version",
+				Description: "Semantic version to check, e.g. \"3.2.1\"",
+			},
+		},
+		Return: function.BoolReturn{},
+	}
+}
+
+// Run checks the version against the constraint.
+func (f *versionMatchesFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var constraint, version string
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &constraint, &version))
+	if resp.Error != nil {
+		return
+	}
+
+	parsedConstraint, err := parseVersionConstraint(constraint)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	parsedVersion, err := parseVersion(version)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, parsedConstraint.Check(parsedVersion)))
+}
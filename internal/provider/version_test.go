@@ -0,0 +1,138 @@
+package provider
+
+import "testing"
+
+func TestParseVersionConstraint(t *testing.T) {
+	valid := []string{
+		"*",
+		"3.*",
+		"3.2.*",
+		"3.2.1",
+		"0.0.0",
+		"3.2.1-rc.1",
+		"3.2.1+build.5",
+		"10.20.30-alpha.1+sha.abc",
+	}
+	for _, constraint := range valid {
+		if _, err := parseVersionConstraint(constraint); err != nil {
+			t.Errorf("%q: unexpected error: %s", constraint, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"3",
+		"3.2",
+		"3.*.1",
+		"*.2",
+		"3.2.1.*",
+		"3.2.1.4",
+		"v3.2.1",
+		"03.2.*",
+		"3.2.x",
+		"latest",
+		"3.2.1-",
+	}
+	for _, constraint := range invalid {
+		if _, err := parseVersionConstraint(constraint); err == nil {
+			t.Errorf("%q: expected an error", constraint)
+		}
+	}
+}
+
+func TestVersionConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"*", "3.2.1", true},
+		{"*", "3.2.1-rc.1", false},
+		{"3.*", "3.0.0", true},
+		{"3.*", "4.0.0", false},
+		{"3.2.*", "3.2.9", true},
+		{"3.2.*", "3.3.0", false},
+		{"3.2.*", "3.2.1-rc.1", false},
+		{"3.2.1", "3.2.1", true},
+		{"3.2.1", "3.2.1+build.5", true},
+		{"3.2.1", "3.2.2", false},
+		{"3.2.1-rc.1", "3.2.1-rc.1", true},
+		{"3.2.1-rc.1", "3.2.1", false},
+	}
+	for _, test := range tests {
+		constraint, err := parseVersionConstraint(test.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err := parseVersion(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := constraint.Check(version); got != test.want {
+			t.Errorf("%q matches %q: got %t, want %t", test.constraint, test.version, got, test.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	// Ordered by precedence as in the semantic versioning specification
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := parseVersion(ordered[i])
+			b, _ := parseVersion(ordered[j])
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := compareVersions(a, b); got != want {
+				t.Errorf("compare %q with %q: got %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	available := []string{"3.1.4", "3.2.0", "3.2.10", "3.2.9", "3.3.0-rc.1", "4.0.0"}
+	tests := map[string]string{
+		"*":          "4.0.0",
+		"3.*":        "3.2.10",
+		"3.2.*":      "3.2.10",
+		"3.1.4":      "3.1.4",
+		"3.3.0-rc.1": "3.3.0-rc.1",
+		"3.3.*":      "",
+		"5.*":        "",
+	}
+	for constraint, want := range tests {
+		parsed, err := parseVersionConstraint(constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok, err := resolveVersion(parsed, available)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want || ok != (want != "") {
+			t.Errorf("%q: got (%q, %t), want %q", constraint, got, ok, want)
+		}
+	}
+
+	parsed, _ := parseVersionConstraint("*")
+	if _, _, err := resolveVersion(parsed, []string{"3.2.1", "latest"}); err == nil {
+		t.Error("expected an error for an invalid available version")
+	}
+}